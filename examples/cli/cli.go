@@ -5,13 +5,18 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"os/signal"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"sync/atomic"
 	"syscall"
 	"text/tabwriter"
+	"time"
 
 	wpa "github.com/CPtung/wpac-go"
 	"github.com/godbus/dbus/v5"
@@ -20,10 +25,16 @@ import (
 
 var (
 	ifname   string
+	ifopts   wpa.InterfaceOptions
+	busAddr  string
+	reveal   bool
+	dryRun   bool
+	hidden   bool
 	cfile    string
 	security string
 	interval int32
 	id       int
+	monitor  wpa.SignalMonitorConfig
 	ctx      context.Context
 	wpacli   *wpa.WPA
 )
@@ -64,6 +75,50 @@ var disconnectReasonCmd = &cobra.Command{
 	Run:   disconnectReasonMode,
 }
 
+var signalPollCmd = &cobra.Command{
+	Use:   "signal_poll",
+	Short: "wpac signal_poll",
+	Run:   signalPollMode,
+}
+
+var eapLogonCmd = &cobra.Command{
+	Use:   "logon",
+	Short: "wpac logon",
+	Run:   eapLogonMode,
+}
+
+var eapLogoffCmd = &cobra.Command{
+	Use:   "logoff",
+	Short: "wpac logoff",
+	Run:   eapLogoffMode,
+}
+
+var eapolStatusCmd = &cobra.Command{
+	Use:   "eapol_status",
+	Short: "wpac eapol_status",
+	Run:   eapolStatusMode,
+}
+
+var capabilitiesCmd = &cobra.Command{
+	Use:   "capabilities",
+	Short: "wpac capabilities",
+	Run:   capabilitiesMode,
+}
+
+var debugLevelCmd = &cobra.Command{
+	Use:   "debug_level [level]",
+	Short: "wpac debug_level",
+	Args:  cobra.MaximumNArgs(1),
+	Run:   debugLevelMode,
+}
+
+var countryCmd = &cobra.Command{
+	Use:   "country [alpha2]",
+	Short: "wpac country",
+	Args:  cobra.MaximumNArgs(1),
+	Run:   countryMode,
+}
+
 var setCmd = &cobra.Command{
 	Use:   "set_network",
 	Short: "wpac set_network",
@@ -76,6 +131,50 @@ var reattachCmd = &cobra.Command{
 	Run:   reattachMode,
 }
 
+var saveConfigCmd = &cobra.Command{
+	Use:   "save_config",
+	Short: "wpac save_config (needs update_config=1)",
+	Run:   saveConfigMode,
+}
+
+var reconcileCmd = &cobra.Command{
+	Use:   "reconcile",
+	Short: "wpac reconcile -c profiles.json [--dry-run]",
+	Run:   reconcileMode,
+}
+
+var flushBSSCmd = &cobra.Command{
+	Use:   "flush_bss [age]",
+	Short: "wpac flush_bss (drop BSSs older than age seconds)",
+	Args:  cobra.MaximumNArgs(1),
+	Run:   flushBSSMode,
+}
+
+var pmksaCmd = &cobra.Command{
+	Use:   "pmksa",
+	Short: "wpac pmksa (list the PMKSA cache)",
+	Run:   pmksaMode,
+}
+
+var pmksaFlushCmd = &cobra.Command{
+	Use:   "pmksa_flush",
+	Short: "wpac pmksa_flush",
+	Run:   pmksaFlushMode,
+}
+
+var anqpCmd = &cobra.Command{
+	Use:   "anqp <bssid>",
+	Short: "wpac anqp (query venue name, nai realms, domain names and roaming consortiums)",
+	Args:  cobra.ExactArgs(1),
+	Run:   anqpMode,
+}
+
+var interworkingSelectCmd = &cobra.Command{
+	Use:   "interworking_select",
+	Short: "wpac interworking_select (connect with a Hotspot 2.0 credential)",
+	Run:   interworkingSelectMode,
+}
+
 var reassociateCmd = &cobra.Command{
 	Use:   "reassociate",
 	Short: "wpac reassociate",
@@ -88,6 +187,60 @@ var removeCmd = &cobra.Command{
 	Run:   removeMode,
 }
 
+var addNetworkCmd = &cobra.Command{
+	Use:   "add_network key=value...",
+	Short: "wpac add_network",
+	Args:  cobra.MinimumNArgs(1),
+	Run:   addNetworkMode,
+}
+
+var selectNetworkCmd = &cobra.Command{
+	Use:   "select_network <id>",
+	Short: "wpac select_network",
+	Args:  cobra.ExactArgs(1),
+	Run:   selectNetworkMode,
+}
+
+var enableNetworkCmd = &cobra.Command{
+	Use:   "enable_network <id>",
+	Short: "wpac enable_network",
+	Args:  cobra.ExactArgs(1),
+	Run:   enableNetworkMode,
+}
+
+var disableNetworkCmd = &cobra.Command{
+	Use:   "disable_network <id>",
+	Short: "wpac disable_network",
+	Args:  cobra.ExactArgs(1),
+	Run:   disableNetworkMode,
+}
+
+var removeNetworkCmd = &cobra.Command{
+	Use:   "remove_network <id>",
+	Short: "wpac remove_network",
+	Args:  cobra.ExactArgs(1),
+	Run:   removeNetworkMode,
+}
+
+var scanIntervalCmd = &cobra.Command{
+	Use:   "scan_interval [seconds]",
+	Short: "wpac scan_interval",
+	Args:  cobra.MaximumNArgs(1),
+	Run:   scanIntervalMode,
+}
+
+var reconnectCmd = &cobra.Command{
+	Use:   "reconnect",
+	Short: "wpac reconnect",
+	Run:   reconnectMode,
+}
+
+var interfacesCmd = &cobra.Command{
+	Use:   "interfaces",
+	Short: "wpac interfaces",
+	Run:   interfacesMode,
+}
+
 var currentBSSCmd = &cobra.Command{
 	Use:   "current_bss",
 	Short: "wpac current_bss",
@@ -113,9 +266,10 @@ var eventCmd = &cobra.Command{
 }
 
 var rootCmd = &cobra.Command{
-	Use:              "wpa",
-	Short:            "WPA Client Util for MOXA ThingsPro",
-	PersistentPreRun: PersistentPreRun,
+	Use:               "wpa",
+	Short:             "WPA Client Util for MOXA ThingsPro",
+	PersistentPreRun:  PersistentPreRun,
+	PersistentPostRun: PersistentPostRun,
 }
 
 func loadConfig(path string, bss *wpa.WPABSS) error {
@@ -136,7 +290,7 @@ func loadConfig(path string, bss *wpa.WPABSS) error {
 			case "bssid":
 				bss.BSSID = matches[2]
 			case "psk":
-				bss.PSK = matches[2]
+				bss.PSK = wpa.Secret(matches[2])
 			}
 		}
 	}
@@ -148,13 +302,20 @@ func loadConfig(path string, bss *wpa.WPABSS) error {
 }
 
 func printUsage(cmd *cobra.Command, err error) {
-	fmt.Println(err.Error())
+	printError(err)
 	cmd.Usage()
+	if inShell {
+		// abort the command but keep the shell session
+		panic(errShellAbort)
+	}
 	os.Exit(1)
 }
 
 func stateMode(cmd *cobra.Command, args []string) {
-	fmt.Printf("network state: %s\n", wpacli.GetInterface(ifname).State())
+	state := wpacli.GetInterface(ifname).State()
+	printOutput(map[string]string{"ifname": ifname, "state": state}, func() {
+		fmt.Printf("network state: %s\n", state)
+	})
 }
 
 func networksMode(cmd *cobra.Command, args []string) {
@@ -162,6 +323,15 @@ func networksMode(cmd *cobra.Command, args []string) {
 	if err != nil {
 		return
 	}
+	list := make([]wpa.WPANetwork, 0, len(networks))
+	for _, network := range networks {
+		list = append(list, network)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	printOutput(list, func() { printNetworks(list) })
+}
+
+func printNetworks(networks []wpa.WPANetwork) {
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
 	fmt.Fprintln(w, "id\tbssid\tssid\tpsk\tkeymgmt\tenable")
 	for _, network := range networks {
@@ -195,7 +365,11 @@ func scanMode(cmd *cobra.Command, args []string) {
 	if err != nil {
 		printUsage(cmd, err)
 	}
+	lastScan = list
+	printOutput(list, func() { printBSSList(list) })
+}
 
+func printBSSList(list []wpa.WPABSS) {
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
 	fmt.Fprintln(w, "bssid\tssid\tfrequency\tsignal")
 	for _, bss := range list {
@@ -214,6 +388,9 @@ func scanMode(cmd *cobra.Command, args []string) {
 				strings.ToUpper(bss.WPA2.KeyMgmt[0]),
 				strings.ToUpper(bss.WPA2.Group)))
 		}
+		if bss.IsOWETransition() {
+			ap.WriteString("\t[OWE-TRANS]")
+		}
 		fmt.Fprintln(w, ap.String())
 	}
 	w.Flush()
@@ -229,14 +406,35 @@ func connectMode(cmd *cobra.Command, args []string) {
 	if err := loadConfig(cfile, &bss); err != nil {
 		printUsage(cmd, err)
 	}
+	if hidden {
+		// security is inferred from the probe response
+		if _, err := wpacli.GetInterface(ifname).ConnectHidden(ctx, bss.SSID, bss.PSK); err != nil {
+			printUsage(cmd, fmt.Errorf("connect hidden network error (%s)", err.Error()))
+		}
+		return
+	}
 
 	switch security {
 	case "none":
 		config = wpa.WPAConfig().GetWPANone(bss)
-	case "wpa":
-		config = wpa.WPAConfig().GetWPA2(bss)
-	case "wpa2":
-		config = wpa.WPAConfig().GetWPA2(bss)
+	case "wpa", "wpa2":
+		var err error
+		if config, err = wpa.WPAConfig().GetWPA2PSK(bss); err != nil {
+			printUsage(cmd, err)
+		}
+	case "owe":
+		config = wpa.WPAConfig().GetOWE(bss)
+	case "wep":
+		var err error
+		if config, err = wpa.WPAConfig().GetWEP(wpa.WEPConfig{SSID: bss.SSID, Keys: [4]wpa.Secret{bss.PSK}}); err != nil {
+			printUsage(cmd, err)
+		}
+	}
+	if err := wpacli.GetInterface(ifname).ValidateProfile(config); err != nil {
+		printUsage(cmd, err)
+	}
+	for _, warning := range wpa.ProfileWarnings(config) {
+		fmt.Fprintf(os.Stderr, "warning: %s\n", warning)
 	}
 	network, err := wpacli.GetInterface(ifname).AddNetwork(config)
 	if err != nil {
@@ -251,28 +449,174 @@ func connectMode(cmd *cobra.Command, args []string) {
 func disconnectMode(cmd *cobra.Command, args []string) {
 	err := wpacli.GetInterface(ifname).Disconnect()
 	if err != nil {
-		fmt.Println(err.Error())
+		printError(err)
 	}
 }
 
 func disconnectReasonMode(cmd *cobra.Command, args []string) {
-	if i, err := wpacli.GetInterface(ifname).DisconnectReason(); err == nil {
-		fmt.Printf("network disconnect code: %d\n", i)
+	var (
+		result struct {
+			Reason      *wpa.ReasonCode `json:"reason"`
+			AuthStatus  *wpa.StatusCode `json:"auth_status"`
+			AssocStatus *wpa.StatusCode `json:"assoc_status"`
+		}
+		reasonErr error
+	)
+	iface := wpacli.GetInterface(ifname)
+	if i, err := iface.DisconnectReason(); err == nil {
+		reason := wpa.DecodeReason(i)
+		result.Reason = &reason
 	} else {
-		fmt.Println(err.Error())
+		reasonErr = err
+	}
+	if i, err := iface.AuthStatusCode(); err == nil {
+		status := wpa.DecodeStatus(i)
+		result.AuthStatus = &status
+	}
+	if i, err := iface.AssocStatusCode(); err == nil {
+		status := wpa.DecodeStatus(i)
+		result.AssocStatus = &status
+	}
+	printOutput(result, func() {
+		if result.Reason != nil {
+			fmt.Printf("network disconnect code: %s\n", result.Reason)
+		} else {
+			fmt.Println(reasonErr.Error())
+		}
+		if result.AuthStatus != nil {
+			fmt.Printf("network auth status: %s\n", result.AuthStatus)
+		}
+		if result.AssocStatus != nil {
+			fmt.Printf("network assoc status: %s\n", result.AssocStatus)
+		}
+	})
+}
+
+func signalPollMode(cmd *cobra.Command, args []string) {
+	lq, err := wpacli.GetInterface(ifname).SignalPoll()
+	if err != nil {
+		printError(err)
+		return
 	}
+	printOutput(lq, func() { printLinkQuality(lq) })
+}
+
+func printLinkQuality(lq wpa.LinkQuality) {
+	fmt.Printf("RSSI=%d\n", lq.RSSI)
+	fmt.Printf("LINKSPEED=%d\n", lq.LinkSpeed)
+	fmt.Printf("NOISE=%d\n", lq.Noise)
+	fmt.Printf("FREQUENCY=%d\n", lq.Frequency)
+	fmt.Printf("WIDTH=%s\n", lq.Width)
+	fmt.Printf("TX_BITRATE=%d\n", lq.TxBitrateKbps)
+	fmt.Printf("RX_BITRATE=%d\n", lq.RxBitrateKbps)
+}
+
+func eapLogonMode(cmd *cobra.Command, args []string) {
+	if err := wpacli.GetInterface(ifname).EAPLogon(); err != nil {
+		printError(err)
+	}
+}
+
+func eapLogoffMode(cmd *cobra.Command, args []string) {
+	if err := wpacli.GetInterface(ifname).EAPLogoff(); err != nil {
+		printError(err)
+	}
+}
+
+func eapolStatusMode(cmd *cobra.Command, args []string) {
+	status := wpacli.GetInterface(ifname).EAPOLStatus()
+	printOutput(status, func() {
+		fmt.Printf("network state: %s\n", status.State)
+		fmt.Printf("auth mode: %s\n", status.AuthMode)
+		fmt.Printf("eap method: %s\n", status.Method)
+		fmt.Printf("completion: %s\n", status.Completion)
+		if status.Status != "" {
+			fmt.Printf("last eap event: %s %s\n", status.Status, status.Parameter)
+		}
+	})
+}
+
+type capabilitiesResult struct {
+	Global    []string                  `json:"global,omitempty"`
+	EAP       []string                  `json:"eap,omitempty"`
+	Interface wpa.InterfaceCapabilities `json:"interface"`
+}
+
+func capabilitiesMode(cmd *cobra.Command, args []string) {
+	result := capabilitiesResult{}
+	if caps, err := wpacli.Capabilities(); err == nil {
+		result.Global = caps
+	}
+	if methods, err := wpacli.EapMethods(); err == nil {
+		result.EAP = methods
+	}
+	caps, err := wpacli.GetInterface(ifname).Capabilities()
+	if err != nil {
+		printError(err)
+		return
+	}
+	result.Interface = caps
+	printOutput(result, func() {
+		if result.Global != nil {
+			fmt.Printf("global: %s\n", strings.Join(result.Global, " "))
+		}
+		if result.EAP != nil {
+			fmt.Printf("eap: %s\n", strings.Join(result.EAP, " "))
+		}
+		fmt.Printf("key_mgmt: %s\n", strings.Join(caps.KeyMgmt, " "))
+		fmt.Printf("pairwise: %s\n", strings.Join(caps.Pairwise, " "))
+		fmt.Printf("group: %s\n", strings.Join(caps.Group, " "))
+		fmt.Printf("protocol: %s\n", strings.Join(caps.Protocol, " "))
+		fmt.Printf("auth_alg: %s\n", strings.Join(caps.AuthAlg, " "))
+		fmt.Printf("scan: %s\n", strings.Join(caps.Scan, " "))
+		fmt.Printf("modes: %s\n", strings.Join(caps.Modes, " "))
+		fmt.Printf("max_scan_ssid: %d\n", caps.MaxScanSSID)
+	})
+}
+
+func debugLevelMode(cmd *cobra.Command, args []string) {
+	if len(args) > 0 {
+		if err := wpacli.SetDebugLevel(args[0]); err != nil {
+			printError(err)
+		}
+		return
+	}
+	level, err := wpacli.DebugLevel()
+	if err != nil {
+		printError(err)
+		return
+	}
+	printOutput(map[string]string{"debug_level": level}, func() {
+		fmt.Printf("debug level: %s\n", level)
+	})
+}
+
+func countryMode(cmd *cobra.Command, args []string) {
+	iface := wpacli.GetInterface(ifname)
+	if len(args) > 0 {
+		if err := iface.SetCountry(args[0]); err != nil {
+			printError(err)
+		}
+		return
+	}
+	country, err := iface.Country()
+	if err != nil {
+		printError(err)
+		return
+	}
+	printOutput(map[string]string{"country": country}, func() {
+		fmt.Printf("country: %s\n", country)
+	})
 }
 
 func currentBSSMode(cmd *cobra.Command, args []string) {
 	b := wpacli.GetInterface(ifname).GetCurrentBSS()
-	fmt.Print(b)
-
+	printOutput(b, func() { printRecord(b) })
 }
 
 func currentNetworkMode(cmd *cobra.Command, args []string) {
 	n := wpacli.GetInterface(ifname).GetCurrentNetwork()
-	fmt.Print(n)
-
+	printOutput(n, func() { printRecord(n) })
 }
 
 func setMode(cmd *cobra.Command, args []string) {
@@ -285,55 +629,272 @@ func setMode(cmd *cobra.Command, args []string) {
 	if err := loadConfig(cfile, &bss); err != nil {
 		printUsage(cmd, err)
 	}
-
 	switch security {
 	case "none":
 		config = wpa.WPAConfig().GetWPANone(bss)
-	case "wpa":
-		config = wpa.WPAConfig().GetWPA2(bss)
-	case "wpa2":
-		config = wpa.WPAConfig().GetWPA2(bss)
+	case "wpa", "wpa2":
+		var err error
+		if config, err = wpa.WPAConfig().GetWPA2PSK(bss); err != nil {
+			printUsage(cmd, err)
+		}
+	case "owe":
+		config = wpa.WPAConfig().GetOWE(bss)
 	}
 	err := wpacli.GetInterface(ifname).SetNetwork(id, config)
 	if err != nil {
-		fmt.Println(err.Error())
+		printError(err)
 	}
 }
 
 func removeMode(cmd *cobra.Command, args []string) {
 	err := wpacli.GetInterface(ifname).RemoveAllNetwork()
 	if err != nil {
-		fmt.Println(err.Error())
+		printError(err)
 	}
 }
 
 func reassociateMode(cmd *cobra.Command, args []string) {
 	err := wpacli.GetInterface(ifname).Reassociate()
 	if err != nil {
-		fmt.Println(err.Error())
+		printError(err)
 	}
 }
 
 func scanIntervalMode(cmd *cobra.Command, args []string) {
-	err := wpacli.GetInterface(ifname).SetScanInterval(interval)
+	iface := wpacli.GetInterface(ifname)
+	if len(args) == 0 {
+		i, err := iface.GetScanInterval()
+		if err != nil {
+			printError(err)
+			return
+		}
+		printOutput(map[string]int32{"scan_interval": i}, func() {
+			fmt.Printf("scan interval: %d\n", i)
+		})
+		return
+	}
+	i, err := strconv.ParseInt(args[0], 10, 32)
+	if err != nil || i < 1 {
+		printUsage(cmd, errors.New("scan interval cannot be smaller than 1sec"))
+	}
+	if err := iface.SetScanInterval(int32(i)); err != nil {
+		printError(err)
+	}
+}
+
+func parseNetworkID(cmd *cobra.Command, arg string) int {
+	i, err := strconv.Atoi(arg)
 	if err != nil {
-		fmt.Println(err.Error())
+		printUsage(cmd, fmt.Errorf("invalid network id %q", arg))
 	}
+	return i
+}
+
+// textFields stay strings even when the value is made of digits
+var textFields = map[string]bool{
+	"ssid": true, "psk": true, "password": true, "sae_password": true,
+	"identity": true, "anonymous_identity": true, "private_key_passwd": true,
+	"id_str": true, "wep_key0": true, "wep_key1": true, "wep_key2": true, "wep_key3": true,
+}
+
+// addNetworkMode takes key=value pairs, e.g. ssid=office psk=secret
+// key_mgmt=WPA-PSK priority=5. Values are converted like Profile.Args:
+// numbers are sent as int32, a 64 hex digit psk and a 10/26 hex digit
+// wep_keyN as raw bytes, everything else as a string wpa_supplicant quotes.
+func addNetworkMode(cmd *cobra.Command, args []string) {
+	settings := make(map[string]interface{})
+	for _, arg := range args {
+		kv := strings.SplitN(arg, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			printUsage(cmd, fmt.Errorf("invalid network parameter %q, expected key=value", arg))
+		}
+		if i, err := strconv.Atoi(kv[1]); err == nil && !textFields[kv[0]] {
+			settings[kv[0]] = i
+		} else {
+			settings[kv[0]] = kv[1]
+		}
+	}
+	config := wpa.Profile{Settings: settings}.Args()
+	iface := wpacli.GetInterface(ifname)
+	if err := iface.ValidateProfile(config); err != nil {
+		printUsage(cmd, err)
+	}
+	network, err := iface.AddNetwork(config)
+	if err != nil {
+		printUsage(cmd, fmt.Errorf("add network error (%s)", err.Error()))
+	}
+	printOutput(map[string]int{"id": network.ID}, func() {
+		fmt.Println(network.ID)
+	})
+}
+
+func selectNetworkMode(cmd *cobra.Command, args []string) {
+	if err := wpacli.GetInterface(ifname).SelectNetwork(parseNetworkID(cmd, args[0])); err != nil {
+		printError(err)
+	}
+}
+
+func enableNetworkMode(cmd *cobra.Command, args []string) {
+	if err := wpacli.GetInterface(ifname).SetNetworkEnabled(parseNetworkID(cmd, args[0]), true); err != nil {
+		printError(err)
+	}
+}
+
+func disableNetworkMode(cmd *cobra.Command, args []string) {
+	if err := wpacli.GetInterface(ifname).SetNetworkEnabled(parseNetworkID(cmd, args[0]), false); err != nil {
+		printError(err)
+	}
+}
+
+func removeNetworkMode(cmd *cobra.Command, args []string) {
+	if err := wpacli.GetInterface(ifname).RemoveNetwork(parseNetworkID(cmd, args[0])); err != nil {
+		printError(err)
+	}
+}
+
+func reconnectMode(cmd *cobra.Command, args []string) {
+	if err := wpacli.GetInterface(ifname).Reconnect(); err != nil {
+		printError(err)
+	}
+}
+
+func interfacesMode(cmd *cobra.Command, args []string) {
+	infos, err := wpacli.GetInterfaces()
+	if err != nil {
+		printError(err)
+		return
+	}
+	printOutput(infos, func() {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+		fmt.Fprintln(w, "ifname\tdriver\tbridge\tpath")
+		for _, info := range infos {
+			bridge := info.BridgeIfname
+			if bridge == "" {
+				bridge = "-"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", info.Ifname, info.Driver, bridge, info.Path)
+		}
+		w.Flush()
+	})
 }
 
 func reattachMode(cmd *cobra.Command, args []string) {
 	err := wpacli.GetInterface(ifname).Reattach()
 	if err != nil {
-		fmt.Println(err.Error())
+		printError(err)
 	}
 }
 
-func reInitInterface(prop map[string]dbus.Variant) {
-	if name, found := prop["Ifname"]; found {
-		fmt.Println("interface (%s) up" + name.Value().(string))
-		if err := wpacli.InitInterface(name.Value().(string)); err != nil {
-			fmt.Printf("add interface error (%s)\n", err.Error())
+func saveConfigMode(cmd *cobra.Command, args []string) {
+	if err := wpacli.GetInterface(ifname).SaveConfig(); err != nil {
+		printError(err)
+	}
+}
+
+// reconcileMode converges the networks to a profile store file
+func reconcileMode(cmd *cobra.Command, args []string) {
+	if cfile == "" {
+		printUsage(cmd, errors.New("profiles file is required"))
+	}
+	store, err := wpa.OpenProfileStore(cfile)
+	if err != nil {
+		printUsage(cmd, err)
+	}
+	iface := wpacli.GetInterface(ifname)
+	var plan wpa.ReconcilePlan
+	if dryRun {
+		plan, err = iface.Plan(ctx, store.List())
+	} else {
+		plan, err = iface.Reconcile(ctx, store.List())
+	}
+	if err != nil {
+		printError(err)
+	}
+	printOutput(plan, func() { fmt.Print(plan.String()) })
+}
+
+func flushBSSMode(cmd *cobra.Command, args []string) {
+	var age uint64
+	if len(args) > 0 {
+		var err error
+		if age, err = strconv.ParseUint(args[0], 10, 32); err != nil {
+			printUsage(cmd, err)
+		}
+	}
+	if err := wpacli.GetInterface(ifname).FlushBSS(uint32(age)); err != nil {
+		printError(err)
+	}
+}
+
+func pmksaMode(cmd *cobra.Command, args []string) {
+	entries, err := wpacli.GetInterface(ifname).GetPMKSA()
+	if err != nil {
+		printError(err)
+		return
+	}
+	printOutput(entries, func() {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+		fmt.Fprintln(w, "bssid\tpmkid\tpmk\tkey_mgmt\texpiration\topportunistic")
+		for _, e := range entries {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%d\t%d\n", e.BSSID, e.PMKID, e.PMK, e.KeyMgmt, e.Expiration, e.Opportunistic)
+		}
+		w.Flush()
+	})
+}
+
+func pmksaFlushMode(cmd *cobra.Command, args []string) {
+	if err := wpacli.GetInterface(ifname).PMKSAFlush(); err != nil {
+		printError(err)
+	}
+}
+
+func anqpMode(cmd *cobra.Command, args []string) {
+	iface := wpacli.GetInterface(ifname)
+	timeout, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	if err := iface.ANQPGet(timeout, args[0], wpa.ANQPVenueName, wpa.ANQPNAIRealm,
+		wpa.ANQPDomainName, wpa.ANQPRoamingConsortium); err != nil {
+		printError(err)
+		return
+	}
+	for _, bss := range iface.GetAllBSSList() {
+		if !strings.EqualFold(bss.BSSID, args[0]) {
+			continue
+		}
+		info, err := bss.ANQP()
+		if err != nil {
+			printError(err)
+			return
 		}
+		printOutput(info, func() {
+			for _, venue := range info.VenueNames {
+				fmt.Printf("venue: %s (%s)\n", venue.Name, venue.Lang)
+			}
+			for _, realm := range info.NAIRealms {
+				fmt.Printf("nai realm: %s eap %v\n", strings.Join(realm.Realms, ";"), realm.EAPMethods)
+			}
+			for _, domain := range info.DomainNames {
+				fmt.Printf("domain: %s\n", domain)
+			}
+			for _, oi := range info.RoamingConsortiums {
+				fmt.Printf("roaming consortium: %s\n", oi)
+			}
+		})
+		return
+	}
+	printError(fmt.Errorf("bss %s not found", args[0]))
+}
+
+func interworkingSelectMode(cmd *cobra.Command, args []string) {
+	if err := wpacli.GetInterface(ifname).InterworkingSelect(); err != nil {
+		printError(err)
+	}
+}
+
+// printInterfaceAdded WPA attaches hotplugged interfaces by itself
+func printInterfaceAdded(prop map[string]dbus.Variant) {
+	if name, found := prop["Ifname"]; found {
+		fmt.Printf("interface (%s) Up\n", name.Value().(string))
 	}
 }
 
@@ -349,22 +910,77 @@ func printState(prop map[string]dbus.Variant) {
 		}
 	}
 	if DisconnectReason, found := prop["DisconnectReason"]; found {
-		fmt.Printf("network Disconnect Code: %d\n", DisconnectReason.Value().(int32))
+		fmt.Printf("network Disconnect Code: %s\n", wpa.DecodeReason(DisconnectReason.Value().(int32)))
+	}
+}
+
+func printEvent(w io.Writer, event wpa.WPAEvent) {
+	switch e := event.(type) {
+	case wpa.Roamed:
+		fmt.Fprintf(w, "network Roamed: %s -> %s (%v)\n", e.From, e.To, e.Duration)
+	case wpa.ServiceDown:
+		fmt.Fprintf(w, "wpa_supplicant Down: %s\n", e.Reason)
+	case wpa.ServiceUp:
+		fmt.Fprintf(w, "wpa_supplicant Up: %s\n", strings.Join(e.Interfaces, ","))
+	case wpa.EAPStatus:
+		fmt.Fprintf(w, "network EAP: %s %s\n", e.Status, e.Parameter)
+	case wpa.SignalDegraded:
+		fmt.Fprintf(w, "network Signal Degraded: %d dBm\n", e.RSSI)
+	case wpa.SignalRecovered:
+		fmt.Fprintf(w, "network Signal Recovered: %d dBm\n", e.RSSI)
+	}
+}
+
+// printSignalLine only reports interface hotplug, everything else is covered by wpac events
+func printSignalLine(event *dbus.Signal) {
+	if len(event.Body) == 0 {
+		return
+	}
+	switch event.Name {
+	case wpa.SignalInterfaceAdded:
+		ifname := ""
+		if len(event.Body) > 1 {
+			if prop, ok := event.Body[1].(map[string]dbus.Variant); ok {
+				if name, found := prop["Ifname"]; found {
+					ifname, _ = name.Value().(string)
+				}
+			}
+		}
+		printEventLine("InterfaceAdded", map[string]interface{}{"path": event.Body[0], "ifname": ifname})
+	case wpa.SignalInterfaceRemoved:
+		printEventLine("InterfaceRemoved", map[string]interface{}{"path": event.Body[0]})
 	}
 }
 
 func eventMode(cmd *cobra.Command, args []string) {
+	if monitor.Degraded != 0 {
+		if err := wpacli.GetInterface(ifname).MonitorSignal(ctx, monitor); err != nil {
+			printUsage(cmd, err)
+		}
+	}
+	if inShell {
+		// the shell prints events itself, don't print them twice meanwhile
+		atomic.StoreInt32(&shellEventsMuted, 1)
+		defer atomic.StoreInt32(&shellEventsMuted, 0)
+	}
 	done := make(chan struct{})
 	go func() {
 		sig := wpacli.GetEventSignal()
-		interrupt := make(chan os.Signal)
+		events := wpacli.SubscribeEvents()
+		defer wpacli.UnsubscribeEvents(events)
+		interrupt := make(chan os.Signal, 1)
 		signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+		defer signal.Stop(interrupt)
 		for {
 			select {
 			case <-interrupt:
 				close(done)
 				return
 			case event := <-sig:
+				if output != outputTable {
+					printSignalLine(event)
+					continue
+				}
 				switch event.Name {
 				case wpa.SignalPropertiesChanged:
 					for _, data := range event.Body {
@@ -377,13 +993,19 @@ func eventMode(cmd *cobra.Command, args []string) {
 				case wpa.SignalInterfaceAdded:
 					for _, data := range event.Body {
 						if prop, ok := data.(map[string]dbus.Variant); ok {
-							reInitInterface(prop)
+							printInterfaceAdded(prop)
 							break
 						}
 					}
 				case wpa.SignalInterfaceRemoved:
 					fmt.Printf("interface (%s) Down\n", event.Body[0])
 				}
+			case event := <-events:
+				if output != outputTable {
+					printEventLine(event.EventName(), event)
+					continue
+				}
+				printEvent(os.Stdout, event)
 			}
 		}
 	}()
@@ -391,34 +1013,100 @@ func eventMode(cmd *cobra.Command, args []string) {
 }
 
 func shutdownMode(cmd *cobra.Command, args []string) {
-	err := wpacli.GetInterface(ifname).CloseInterface()
-	if err != nil {
-		fmt.Println(err.Error())
+	if err := wpacli.RemoveInterface(ifname); err != nil {
+		printError(err)
 	}
-	wpacli.RemoveInterface(ifname)
 }
 
 func PersistentPreRun(cmd *cobra.Command, args []string) {
-	if err := wpacli.InitInterface(ifname); err != nil {
-		log.Fatalf(err.Error())
+	var err error
+	if inShell {
+		// the shell keeps its connection, only attach interfaces given by -i
+		if wpacli.GetInterface(ifname) == nil {
+			if err := wpacli.InitInterface(ifname); err != nil {
+				printUsage(cmd, err)
+			}
+		}
+		if err := checkOutput(); err != nil {
+			printUsage(cmd, err)
+		}
+		wpa.RevealSecrets(reveal)
+		return
+	}
+	if err = checkOutput(); err != nil {
+		printUsage(cmd, err)
+	}
+	wpa.RevealSecrets(reveal)
+	opts := []wpa.Option{wpa.WithActivation(), wpa.WithWaitForService(10 * time.Second)}
+	if busAddr != "" {
+		opts = append(opts, wpa.WithBusAddress(busAddr))
+	}
+	if wpacli, err = wpa.NewWPA(ctx, opts...); err != nil {
+		log.Fatal(err)
+	}
+
+	ifopts.Ifname = ifname
+	if ifopts.Driver == wpa.DriverWired {
+		if err := wpacli.InitWiredInterface(ifname); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if err := wpacli.InitInterfaceWithOptions(ifopts); err != nil {
+		log.Fatal(err)
 	}
 }
 
 func init() {
 	rootCmd.PersistentFlags().StringVarP(&ifname, "iface", "i", "wlan0", "target interface")
+	rootCmd.PersistentFlags().StringVarP(&ifopts.Driver, "driver", "D", "nl80211", "interface driver, e.g. \"nl80211,wext\" or \"wired\"")
+	rootCmd.PersistentFlags().StringVarP(&ifopts.BridgeIfname, "bridge", "b", "", "bridge interface")
+	rootCmd.PersistentFlags().StringVar(&ifopts.ConfigFile, "conf", "", "wpa_supplicant configuration file of the interface")
+	rootCmd.PersistentFlags().StringVarP(&output, "output", "o", outputTable, "output format (\"json\", \"yaml\", \"table\")")
+	rootCmd.PersistentFlags().StringVar(&busAddr, "bus-address", "", "dbus address, e.g. \"unix:path=/run/dbus/system_bus_socket\"")
+	rootCmd.PersistentFlags().BoolVar(&reveal, "show-secrets", false, "print passphrases and passwords in clear text")
 	connectCmd.Flags().StringVarP(&cfile, "config", "c", "", "target network config")
-	connectCmd.Flags().StringVarP(&security, "security", "s", "wpa2", "target network security (\"none\", \"wpa\", \"wpa2\")")
+	connectCmd.Flags().BoolVar(&hidden, "hidden", false, "probe for a non-broadcast ssid, --security is inferred")
+	connectCmd.Flags().StringVarP(&security, "security", "s", "wpa2", "target network security (\"none\", \"wpa\", \"wpa2\", \"owe\", \"wep\")")
 	scanCmd.Flags().Int32VarP(&interval, "interval", "I", -1, "target scan interval (interval > 0)")
 	setCmd.Flags().IntVar(&id, "id", 0, "target network id")
+	eventCmd.Flags().Int32Var(&monitor.Degraded, "degraded", 0, "signal degraded threshold in dBm (0 disables signal monitor)")
+	eventCmd.Flags().Int32Var(&monitor.Recovered, "recovered", 0, "signal recovered threshold in dBm (0 is degraded + 5)")
+	eventCmd.Flags().DurationVar(&monitor.Interval, "poll", 0, "signal poll interval (0 follows bss signal updates)")
 	setCmd.Flags().StringVarP(&cfile, "config", "c", "", "target network config")
+	reconcileCmd.Flags().StringVarP(&cfile, "config", "c", "", "profiles file, e.g. a profile store")
+	reconcileCmd.Flags().BoolVar(&dryRun, "dry-run", false, "print the plan without changing anything")
 	rootCmd.AddCommand(stateCmd)
 	rootCmd.AddCommand(scanCmd)
 	rootCmd.AddCommand(networksCmd)
 	rootCmd.AddCommand(connectCmd)
 	rootCmd.AddCommand(disconnectCmd)
 	rootCmd.AddCommand(disconnectReasonCmd)
+	rootCmd.AddCommand(signalPollCmd)
+	rootCmd.AddCommand(eapLogonCmd)
+	rootCmd.AddCommand(eapLogoffCmd)
+	rootCmd.AddCommand(eapolStatusCmd)
+	rootCmd.AddCommand(capabilitiesCmd)
+	rootCmd.AddCommand(addNetworkCmd)
+	rootCmd.AddCommand(selectNetworkCmd)
+	rootCmd.AddCommand(enableNetworkCmd)
+	rootCmd.AddCommand(disableNetworkCmd)
+	rootCmd.AddCommand(removeNetworkCmd)
+	rootCmd.AddCommand(scanIntervalCmd)
+	rootCmd.AddCommand(reconnectCmd)
+	rootCmd.AddCommand(interfacesCmd)
+	rootCmd.AddCommand(shellCmd)
+	rootCmd.AddCommand(debugLevelCmd)
+	rootCmd.AddCommand(countryCmd)
 	rootCmd.AddCommand(setCmd)
 	rootCmd.AddCommand(reattachCmd)
+	rootCmd.AddCommand(saveConfigCmd)
+	rootCmd.AddCommand(reconcileCmd)
+	rootCmd.AddCommand(flushBSSCmd)
+	rootCmd.AddCommand(pmksaCmd)
+	rootCmd.AddCommand(pmksaFlushCmd)
+	rootCmd.AddCommand(anqpCmd)
+	rootCmd.AddCommand(interworkingSelectCmd)
 	rootCmd.AddCommand(reassociateCmd)
 	rootCmd.AddCommand(removeCmd)
 	rootCmd.AddCommand(shutdownCmd)
@@ -427,13 +1115,15 @@ func init() {
 	rootCmd.AddCommand(currentNetworkCmd)
 }
 
-func main() {
-	var err error
-	if wpacli, err = wpa.NewWPA(context.TODO()); err != nil {
-		log.Fatalf(err.Error())
+func PersistentPostRun(cmd *cobra.Command, args []string) {
+	if inShell {
+		return
 	}
-	defer wpacli.Close()
+	wpacli.Close()
+}
 
+func main() {
+	ctx = context.TODO()
 	if err := rootCmd.Execute(); err != nil {
 		os.Exit(1)
 	}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+)
+
+var output string
+
+func checkOutput() error {
+	switch output {
+	case outputTable, outputJSON, outputYAML:
+		return nil
+	}
+	return fmt.Errorf("unknown output format %q (json, yaml, table)", output)
+}
+
+// toGeneric converts v through its json tags so json and yaml share field names
+func toGeneric(v interface{}) (interface{}, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var generic interface{}
+	if err := json.Unmarshal(b, &generic); err != nil {
+		return nil, err
+	}
+	return generic, nil
+}
+
+// printOutput prints v as json or yaml, table calls the command's own layout
+func printOutput(v interface{}, table func()) {
+	switch output {
+	case outputJSON:
+		b, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			fmt.Println(err.Error())
+			return
+		}
+		fmt.Println(string(b))
+	case outputYAML:
+		generic, err := toGeneric(v)
+		if err != nil {
+			fmt.Println(err.Error())
+			return
+		}
+		b, err := yaml.Marshal(generic)
+		if err != nil {
+			fmt.Println(err.Error())
+			return
+		}
+		fmt.Print(string(b))
+	default:
+		table()
+	}
+}
+
+// printError prints err as {"error": ...} in json and yaml, so scripts can parse failures
+func printError(err error) {
+	switch output {
+	case outputJSON, outputYAML:
+		printOutput(map[string]string{"error": err.Error()}, nil)
+	default:
+		fmt.Println(err.Error())
+	}
+}
+
+// printRecord prints a single struct as a key/value table
+func printRecord(v interface{}) {
+	generic, err := toGeneric(v)
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+	record, ok := generic.(map[string]interface{})
+	if !ok {
+		fmt.Println(generic)
+		return
+	}
+	keys := make([]string, 0, len(record))
+	for k := range record {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	for _, k := range keys {
+		value := record[k]
+		if value == nil {
+			value = "-"
+		}
+		fmt.Fprintf(w, "%s\t%v\n", k, value)
+	}
+	w.Flush()
+}
+
+// printEventLine prints one event per line in json, or one document per event in yaml
+func printEventLine(name string, v interface{}) {
+	generic, err := toGeneric(v)
+	if err != nil {
+		return
+	}
+	record, ok := generic.(map[string]interface{})
+	if !ok {
+		record = map[string]interface{}{"data": generic}
+	}
+	record["event"] = name
+	record["time"] = time.Now().Format(time.RFC3339)
+	switch output {
+	case outputJSON:
+		b, _ := json.Marshal(record)
+		fmt.Println(string(b))
+	case outputYAML:
+		b, _ := yaml.Marshal(record)
+		fmt.Printf("---\n%s", b)
+	}
+}
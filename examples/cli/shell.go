@@ -0,0 +1,290 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"unicode"
+
+	wpa "github.com/CPtung/wpac-go"
+	"github.com/chzyer/readline"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+var (
+	inShell       bool
+	errShellAbort = errors.New("command aborted")
+	lastScan      []wpa.WPABSS
+	// shellEventsMuted is set while the event command prints events itself
+	shellEventsMuted int32
+)
+
+const shellName = "shell"
+
+var shellCmd = &cobra.Command{
+	Use:   shellName,
+	Short: "wpac interactive shell",
+	Run:   shellMode,
+}
+
+// networkIDCommands complete their argument with the ids of configured networks
+var networkIDCommands = map[string]bool{
+	"select_network":  true,
+	"enable_network":  true,
+	"disable_network": true,
+	"remove_network":  true,
+	"set_network":     true,
+}
+
+func networkIDs(string) []string {
+	iface := wpacli.GetInterface(ifname)
+	if iface == nil {
+		return nil
+	}
+	networks, err := iface.GetNetworks()
+	if err != nil {
+		return nil
+	}
+	ids := make([]int, 0, len(networks))
+	for id := range networks {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	list := make([]string, 0, len(ids))
+	for _, id := range ids {
+		list = append(list, strconv.Itoa(id))
+	}
+	return list
+}
+
+// scannedSSIDs completes ssid= with the SSIDs of the last scan in this shell
+func scannedSSIDs(string) []string {
+	list := make([]string, 0, len(lastScan))
+	seen := make(map[string]bool)
+	for _, bss := range lastScan {
+		if !seen[bss.SSID] {
+			seen[bss.SSID] = true
+			list = append(list, "ssid="+bss.SSID)
+		}
+	}
+	return list
+}
+
+func shellCompleter() *readline.PrefixCompleter {
+	items := []readline.PrefixCompleterInterface{
+		readline.PcItem("exit"),
+		readline.PcItem("quit"),
+	}
+	for _, c := range rootCmd.Commands() {
+		name := c.Name()
+		switch {
+		case name == shellName:
+			continue
+		case networkIDCommands[name]:
+			items = append(items, readline.PcItem(name, readline.PcItemDynamic(networkIDs)))
+		case name == "add_network":
+			items = append(items, readline.PcItem(name, readline.PcItemDynamic(scannedSSIDs)))
+		default:
+			items = append(items, readline.PcItem(name))
+		}
+	}
+	return readline.NewPrefixCompleter(items...)
+}
+
+// printShellEvent also prints the state changes event mode reads from raw signals
+func printShellEvent(w io.Writer, event wpa.WPAEvent) {
+	switch e := event.(type) {
+	case wpa.StateChanged:
+		fmt.Fprintf(w, "<%s> network State: %s\n", e.Ifname, e.State)
+	case wpa.Disconnected:
+		fmt.Fprintf(w, "<%s> network Disconnect Code: %s\n", e.Ifname, e.Decode())
+	case wpa.ScanFinished:
+		fmt.Fprintf(w, "<%s> network State: scan completed (success=%v)\n", e.Ifname, e.Success)
+	case wpa.AuthFailed:
+		if e.Reason != 0 {
+			fmt.Fprintf(w, "<%s> network Auth Failed in %s: %s\n", e.Ifname, e.State, wpa.DecodeReason(e.Reason))
+		} else {
+			fmt.Fprintf(w, "<%s> network Auth Failed in %s\n", e.Ifname, e.State)
+		}
+	default:
+		printEvent(w, event)
+	}
+}
+
+// snapshotFlags remembers the flag values the shell was started with, they are
+// restored before every command so flags of one command don't leak into the next.
+func snapshotFlags() map[*pflag.Flag]string {
+	values := make(map[*pflag.Flag]string)
+	record := func(f *pflag.Flag) {
+		values[f] = f.Value.String()
+	}
+	rootCmd.PersistentFlags().VisitAll(record)
+	for _, c := range rootCmd.Commands() {
+		c.Flags().VisitAll(func(f *pflag.Flag) {
+			if _, found := values[f]; !found {
+				values[f] = f.DefValue
+			}
+		})
+	}
+	return values
+}
+
+func restoreFlags(values map[*pflag.Flag]string) {
+	for f, v := range values {
+		f.Value.Set(v)
+		f.Changed = false
+	}
+}
+
+// splitWords splits line like a POSIX shell, so quoted arguments such as
+// ssid="my network" keep their spaces. Single quotes are literal, a backslash
+// escapes the next character outside of them.
+func splitWords(line string) ([]string, error) {
+	words := []string{}
+	word := strings.Builder{}
+	inWord := false
+	var quote rune
+	escaped := false
+	for _, r := range line {
+		switch {
+		case escaped:
+			word.WriteRune(r)
+			escaped = false
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '\\':
+			escaped, inWord = true, true
+		case quote == '"':
+			if r == '"' {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote, inWord = r, true
+		case unicode.IsSpace(r):
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote", quote)
+	}
+	if escaped {
+		return nil, errors.New("trailing backslash")
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
+// secretArgs are the key=value arguments which never go into the history file
+var secretArgs = map[string]bool{
+	"psk": true, "password": true, "sae_password": true, "private_key_passwd": true,
+	"passphrase": true, "wep_key0": true, "wep_key1": true, "wep_key2": true, "wep_key3": true,
+}
+
+func keepInHistory(fields []string) bool {
+	for _, field := range fields {
+		if kv := strings.SplitN(field, "=", 2); len(kv) == 2 && secretArgs[kv[0]] {
+			return false
+		}
+	}
+	return true
+}
+
+func runShellCommand(args []string) {
+	defer func() {
+		if r := recover(); r != nil && r != errShellAbort {
+			fmt.Printf("%v\n", r)
+		}
+	}()
+	rootCmd.SetArgs(args)
+	rootCmd.Execute()
+}
+
+func shellMode(cmd *cobra.Command, args []string) {
+	home, _ := os.UserHomeDir()
+	rl, err := readline.NewEx(&readline.Config{
+		Prompt:          "> ",
+		HistoryFile:     filepath.Join(home, ".wpac_history"),
+		AutoComplete:    shellCompleter(),
+		InterruptPrompt: "^C",
+		EOFPrompt:       "exit",
+		// lines are saved by keepInHistory, secrets stay off the disk
+		DisableAutoSaveHistory: true,
+	})
+	if err != nil {
+		printUsage(cmd, err)
+	}
+	defer rl.Close()
+
+	events := wpacli.SubscribeEvents()
+	done := make(chan struct{})
+	defer func() {
+		close(done)
+		wpacli.UnsubscribeEvents(events)
+	}()
+	go func() {
+		for {
+			select {
+			case event := <-events:
+				if atomic.LoadInt32(&shellEventsMuted) == 0 {
+					printShellEvent(rl.Stdout(), event)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	flags := snapshotFlags()
+	inShell = true
+	defer func() { inShell = false }()
+	for {
+		line, err := rl.Readline()
+		if err == readline.ErrInterrupt {
+			continue
+		} else if err != nil {
+			return
+		}
+		fields, err := splitWords(line)
+		if err != nil {
+			fmt.Println(err.Error())
+			continue
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		if keepInHistory(fields) {
+			rl.SaveHistory(line)
+		}
+		switch fields[0] {
+		case "exit", "quit":
+			return
+		case shellName:
+			fmt.Println("already in shell")
+			continue
+		}
+		restoreFlags(flags)
+		runShellCommand(fields)
+	}
+}
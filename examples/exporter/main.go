@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"net/http"
+	"strings"
+
+	wpa "github.com/CPtung/wpac-go"
+	"github.com/CPtung/wpac-go/exporter"
+)
+
+func main() {
+	var (
+		addr    string
+		ifnames string
+	)
+	flag.StringVar(&addr, "listen", ":9426", "metrics listen address")
+	flag.StringVar(&ifnames, "iface", "wlan0", "comma separated interfaces to export")
+	flag.Parse()
+
+	ctx := context.Background()
+	wpacli, err := wpa.NewWPA(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer wpacli.Close()
+
+	names := strings.Split(ifnames, ",")
+	for _, name := range names {
+		if err := wpacli.InitInterface(name); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	e := exporter.New(wpacli, names...)
+	go e.Run(ctx)
+
+	http.Handle("/metrics", e)
+	log.Fatal(http.ListenAndServe(addr, nil))
+}
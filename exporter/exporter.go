@@ -0,0 +1,230 @@
+// Package exporter publishes wpac interface health in the Prometheus text
+// exposition format.
+package exporter
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	wpa "github.com/CPtung/wpac-go"
+)
+
+const contentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// States reported by the wpac_state gauge, one series per state
+var States = []string{
+	"disconnected",
+	"interface_disabled",
+	"inactive",
+	"scanning",
+	"authenticating",
+	"associating",
+	"associated",
+	"4way_handshake",
+	"group_handshake",
+	"completed",
+	"unknown",
+}
+
+type disconnectKey struct {
+	ifname string
+	reason int32
+}
+
+// Exporter gauges are read from wpa_supplicant on every scrape, counters are
+// accumulated from wpac events while Run is active.
+type Exporter struct {
+	wpa     *wpa.WPA
+	ifnames []string
+
+	mu           sync.Mutex
+	connects     map[string]uint64
+	disconnects  map[disconnectKey]uint64
+	scanFailures map[string]uint64
+	authFailures map[string]uint64
+	roams        map[string]uint64
+	// linked interfaces, wpa_supplicant reports completed again after a roam
+	linked map[string]bool
+}
+
+func New(wpacli *wpa.WPA, ifnames ...string) *Exporter {
+	return &Exporter{
+		wpa:          wpacli,
+		ifnames:      ifnames,
+		connects:     make(map[string]uint64),
+		disconnects:  make(map[disconnectKey]uint64),
+		scanFailures: make(map[string]uint64),
+		authFailures: make(map[string]uint64),
+		roams:        make(map[string]uint64),
+		linked:       make(map[string]bool),
+	}
+}
+
+// Run counts events until ctx is done
+func (e *Exporter) Run(ctx context.Context) {
+	events := e.wpa.SubscribeEvents()
+	defer e.wpa.UnsubscribeEvents(events)
+	for {
+		select {
+		case event := <-events:
+			e.count(event)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (e *Exporter) count(event wpa.WPAEvent) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	switch ev := event.(type) {
+	case wpa.Connected:
+		if !e.linked[ev.Ifname] {
+			e.connects[ev.Ifname]++
+			e.linked[ev.Ifname] = true
+		}
+	case wpa.StateChanged:
+		switch ev.State {
+		case "disconnected", "inactive", "interface_disabled", "scanning":
+			// a roam goes through the authentication states only
+			e.linked[ev.Ifname] = false
+		}
+	case wpa.Disconnected:
+		e.disconnects[disconnectKey{ev.Ifname, ev.Reason}]++
+	case wpa.ScanFinished:
+		if !ev.Success {
+			e.scanFailures[ev.Ifname]++
+		}
+	case wpa.AuthFailed:
+		e.authFailures[ev.Ifname]++
+	case wpa.Roamed:
+		e.roams[ev.Ifname]++
+	}
+}
+
+func (e *Exporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var buf bytes.Buffer
+	e.writeGauges(&buf)
+	e.writeCounters(&buf)
+	w.Header().Set("Content-Type", contentType)
+	w.Write(buf.Bytes())
+}
+
+func (e *Exporter) writeGauges(buf *bytes.Buffer) {
+	type sample struct {
+		ifname string
+		value  float64
+	}
+	var (
+		states    = map[string]string{}
+		rssi      []sample
+		linkSpeed []sample
+		frequency []sample
+		bssCount  []sample
+	)
+	for _, ifname := range e.ifnames {
+		iface := e.wpa.GetInterface(ifname)
+		if iface == nil {
+			continue
+		}
+		states[ifname] = iface.State()
+		bssCount = append(bssCount, sample{ifname, float64(len(iface.GetBSSList()))})
+		if lq, err := iface.SignalPoll(); err == nil {
+			rssi = append(rssi, sample{ifname, float64(lq.RSSI)})
+			linkSpeed = append(linkSpeed, sample{ifname, float64(lq.LinkSpeed)})
+			frequency = append(frequency, sample{ifname, float64(lq.Frequency)})
+		}
+	}
+
+	writeHeader(buf, "wpac_state", "gauge", "Current wpa_supplicant interface state.")
+	for _, ifname := range e.ifnames {
+		current, found := states[ifname]
+		if !found {
+			continue
+		}
+		for _, state := range States {
+			value := 0
+			if state == current {
+				value = 1
+			}
+			fmt.Fprintf(buf, "wpac_state{ifname=\"%s\",state=\"%s\"} %d\n", escape(ifname), state, value)
+		}
+	}
+
+	gauges := []struct {
+		name, help string
+		samples    []sample
+	}{
+		{"wpac_rssi_dbm", "Signal strength of the current link in dBm.", rssi},
+		{"wpac_link_speed_mbps", "Link speed of the current link in Mbps.", linkSpeed},
+		{"wpac_frequency_mhz", "Frequency of the current link in MHz.", frequency},
+		{"wpac_bss_count", "Number of BSSes visible in the last scan results.", bssCount},
+	}
+	for _, g := range gauges {
+		writeHeader(buf, g.name, "gauge", g.help)
+		for _, s := range g.samples {
+			fmt.Fprintf(buf, "%s{ifname=\"%s\"} %g\n", g.name, escape(s.ifname), s.value)
+		}
+	}
+}
+
+func (e *Exporter) writeCounters(buf *bytes.Buffer) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	counters := []struct {
+		name, help string
+		values     map[string]uint64
+	}{
+		{"wpac_connects_total", "Number of connections, roams are counted by wpac_roams_total.", e.connects},
+		{"wpac_scan_failures_total", "Number of failed scans.", e.scanFailures},
+		{"wpac_auth_failures_total", "Number of authentication failures.", e.authFailures},
+		{"wpac_roams_total", "Number of roams between BSSes.", e.roams},
+	}
+	for _, c := range counters {
+		writeHeader(buf, c.name, "counter", c.help)
+		for _, ifname := range sortedKeys(c.values) {
+			fmt.Fprintf(buf, "%s{ifname=\"%s\"} %d\n", c.name, escape(ifname), c.values[ifname])
+		}
+	}
+
+	writeHeader(buf, "wpac_disconnects_total", "counter", "Number of disconnections by DisconnectReason.")
+	keys := make([]disconnectKey, 0, len(e.disconnects))
+	for k := range e.disconnects {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].ifname != keys[j].ifname {
+			return keys[i].ifname < keys[j].ifname
+		}
+		return keys[i].reason < keys[j].reason
+	})
+	for _, k := range keys {
+		fmt.Fprintf(buf, "wpac_disconnects_total{ifname=\"%s\",reason=\"%d\"} %d\n", escape(k.ifname), k.reason, e.disconnects[k])
+	}
+}
+
+func writeHeader(buf *bytes.Buffer, name, kind, help string) {
+	fmt.Fprintf(buf, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(buf, "# TYPE %s %s\n", name, kind)
+}
+
+func sortedKeys(m map[string]uint64) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escape(s string) string {
+	return labelEscaper.Replace(s)
+}
@@ -0,0 +1,47 @@
+package exporter
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	wpa "github.com/CPtung/wpac-go"
+)
+
+func TestCountEvents(t *testing.T) {
+	e := New(nil, "wlan0")
+	events := []wpa.WPAEvent{
+		wpa.StateChanged{Ifname: "wlan0", State: "completed", Previous: "4way_handshake"},
+		wpa.Connected{Ifname: "wlan0"},
+		// a roam, wpa_supplicant reports completed once more
+		wpa.StateChanged{Ifname: "wlan0", State: "authenticating", Previous: "completed"},
+		wpa.StateChanged{Ifname: "wlan0", State: "completed", Previous: "authenticating"},
+		wpa.Connected{Ifname: "wlan0"},
+		wpa.Roamed{Ifname: "wlan0", From: "00:11:22:33:44:55", To: "00:11:22:33:44:66"},
+		wpa.Disconnected{Ifname: "wlan0", Reason: 3},
+		wpa.StateChanged{Ifname: "wlan0", State: "disconnected", Previous: "completed"},
+		wpa.ScanFinished{Ifname: "wlan0", Success: false},
+		wpa.ScanFinished{Ifname: "wlan0", Success: true},
+		wpa.AuthFailed{Ifname: "wlan0", State: "4way_handshake", Reason: 15},
+		wpa.StateChanged{Ifname: "wlan0", State: "completed", Previous: "group_handshake"},
+		wpa.Connected{Ifname: "wlan0"},
+	}
+	for _, event := range events {
+		e.count(event)
+	}
+
+	var buf bytes.Buffer
+	e.writeCounters(&buf)
+	out := buf.String()
+	for _, want := range []string{
+		`wpac_connects_total{ifname="wlan0"} 2`,
+		`wpac_roams_total{ifname="wlan0"} 1`,
+		`wpac_scan_failures_total{ifname="wlan0"} 1`,
+		`wpac_auth_failures_total{ifname="wlan0"} 1`,
+		`wpac_disconnects_total{ifname="wlan0",reason="3"} 1`,
+	} {
+		if !strings.Contains(out, want+"\n") {
+			t.Errorf("missing %s in\n%s", want, out)
+		}
+	}
+}
@@ -1,6 +1,9 @@
 module github.com/CPtung/wpac-go
 
 require (
+	github.com/chzyer/readline v1.5.1
 	github.com/godbus/dbus/v5 v5.0.3
 	github.com/spf13/cobra v1.0.0
+	github.com/spf13/pflag v1.0.3
+	gopkg.in/yaml.v2 v2.2.2
 )
@@ -2,65 +2,342 @@ package wpac
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
 
 	"github.com/godbus/dbus/v5"
 )
 
 // WPA ...
 type WPA struct {
-	bus    *WPADBus
-	ctx    context.Context
-	ifaces map[string]*WPAInterface
+	mu        sync.Mutex
+	bus       *WPADBus
+	ctx       context.Context
+	cancel    context.CancelFunc
+	ifaces    map[string]*WPAInterface
+	onAdded   func(*WPAInterface)
+	onRemoved func(string)
+	reapply   bool
+	profiles  *ProfileStore
+	conflict  ConflictPolicy
 }
 
-// NewWPA ...
-func NewWPA(ctx context.Context) (wpa *WPA, e error) {
+const reconnectInterval = 2 * time.Second
+
+// NewWPA connects to wpa_supplicant, it fails with ErrServiceNotRunning if
+// wpa_supplicant isn't on the bus unless WithActivation or WithWaitForService is given.
+func NewWPA(ctx context.Context, opts ...Option) (wpa *WPA, e error) {
 	var (
 		err error
 		bus *WPADBus
+		o   options
 	)
-	bus, err = NewWpaDBus(ctx)
+	for _, opt := range opts {
+		opt(&o)
+	}
+	switch {
+	case o.conn != nil:
+		bus, err = NewWpaDBusWithConn(o.conn, false, nil)
+	case o.dial != nil:
+		var conn *dbus.Conn
+		if conn, err = o.dial(); err != nil {
+			return nil, fmt.Errorf("%w (%s)", ErrBusUnavailable, err.Error())
+		}
+		bus, err = NewWpaDBusWithConn(conn, true, o.dial)
+	default:
+		bus, err = NewWpaDBus(ctx)
+	}
 	if err != nil {
 		return nil, err
 	}
+	bus.secrets = o.secrets
+	if err = waitForService(ctx, bus, o); err != nil {
+		bus.Close()
+		return nil, err
+	}
 	// init wpa instance
+	ctx, cancel := context.WithCancel(ctx)
 	wpa = &WPA{
-		bus:    bus,
-		ctx:    ctx,
-		ifaces: make(map[string]*WPAInterface),
+		bus:      bus,
+		ctx:      ctx,
+		cancel:   cancel,
+		ifaces:   make(map[string]*WPAInterface),
+		profiles: o.profiles,
+		conflict: o.conflict,
 	}
+	go wpa.watcher(bus.Subscribe())
 	return wpa, e
 }
 
 func (w *WPA) InitInterface(ifname string) error {
+	return w.InitInterfaceWithOptions(InterfaceOptions{Ifname: ifname})
+}
+
+// InitInterfaceWithOptions e.g. a wired 802.1X port or a bridged interface
+func (w *WPA) InitInterfaceWithOptions(opts InterfaceOptions) error {
 	iface := NewWPAInterface(w.ctx, w.bus)
-	if err := iface.CreateInterface(ifname); err != nil {
+	if err := iface.CreateInterfaceWithOptions(opts); err != nil {
+		iface.Detach()
+		return err
+	}
+	return w.attach(iface)
+}
+
+// InitInterfaces attaches every interface wpa_supplicant currently controls
+func (w *WPA) InitInterfaces() error {
+	infos, err := w.GetInterfaces()
+	if err != nil {
 		return err
 	}
+	for _, info := range infos {
+		if err := w.InitInterface(info.Ifname); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
+func (w *WPA) attach(iface *WPAInterface) error {
 	// scan wpa network profiles on machine
 	if _, err := iface.GetNetworks(); err != nil {
+		iface.Detach()
 		return err
 	}
+	if w.profiles != nil {
+		if err := iface.SyncProfiles(w.profiles, w.conflict); err != nil {
+			iface.Detach()
+			return err
+		}
+	}
 
 	if err := iface.AddEventListener(); err != nil {
+		iface.Detach()
 		return err
 	}
 
-	w.ifaces[ifname] = iface
+	w.mu.Lock()
+	if old, found := w.ifaces[iface.ifname]; found {
+		if old.Path() == iface.Path() {
+			// already attached, e.g. by the interface watcher
+			w.mu.Unlock()
+			iface.Detach()
+			return nil
+		}
+		old.Detach()
+	}
+	w.ifaces[iface.ifname] = iface
+	onAdded := w.onAdded
+	w.mu.Unlock()
+
+	if onAdded != nil {
+		onAdded(iface)
+	}
 	return nil
 }
 
+func (w *WPA) detach(ifname string) *WPAInterface {
+	w.mu.Lock()
+	iface, found := w.ifaces[ifname]
+	if found {
+		delete(w.ifaces, ifname)
+	}
+	onRemoved := w.onRemoved
+	w.mu.Unlock()
+
+	if !found {
+		return nil
+	}
+	iface.Detach()
+	if onRemoved != nil {
+		onRemoved(ifname)
+	}
+	return iface
+}
+
 func (w *WPA) GetInterface(ifname string) *WPAInterface {
+	w.mu.Lock()
+	defer w.mu.Unlock()
 	if iface, found := w.ifaces[ifname]; found {
 		return iface
 	}
 	return nil
 }
 
-func (w *WPA) RemoveInterface(ifname string) {
-	if _, found := w.ifaces[ifname]; found {
-		delete(w.ifaces, ifname)
+// GetInterfaces lists every interface controlled by wpa_supplicant, attached or not
+func (w *WPA) GetInterfaces() ([]InterfaceInfo, error) {
+	prop, err := w.bus.GetProperty("fi.w1.wpa_supplicant1.Interfaces")
+	if err != nil {
+		return nil, err
+	}
+	paths, ok := prop.([]dbus.ObjectPath)
+	if !ok {
+		return nil, errors.New("get interfaces error")
+	}
+	infos := make([]InterfaceInfo, 0, len(paths))
+	for _, path := range paths {
+		info, err := GetInterfaceInfo(w.bus, path)
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+// RemoveInterface removes the interface from wpa_supplicant and detaches it,
+// the interface stays attached if wpa_supplicant refuses
+func (w *WPA) RemoveInterface(ifname string) error {
+	iface := w.GetInterface(ifname)
+	if iface == nil {
+		return errors.New("interface not found")
+	}
+	if err := iface.CloseInterface(); err != nil {
+		return err
+	}
+	// the InterfaceRemoved watcher may have detached it already
+	w.detach(ifname)
+	return nil
+}
+
+// OnInterfaceAdded is called whenever an interface is attached, including hotplugged ones
+func (w *WPA) OnInterfaceAdded(fn func(*WPAInterface)) {
+	w.mu.Lock()
+	w.onAdded = fn
+	w.mu.Unlock()
+}
+
+// OnInterfaceRemoved is called whenever an interface is detached
+func (w *WPA) OnInterfaceRemoved(fn func(ifname string)) {
+	w.mu.Lock()
+	w.onRemoved = fn
+	w.mu.Unlock()
+}
+
+func (w *WPA) ifnameByPath(path dbus.ObjectPath) (string, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for ifname, iface := range w.ifaces {
+		if iface.Path() == path {
+			return ifname, true
+		}
+	}
+	return "", false
+}
+
+// SetReapplyNetworks re-adds the networks added through wpac once
+// wpa_supplicant comes back after a restart.
+func (w *WPA) SetReapplyNetworks(enable bool) {
+	w.mu.Lock()
+	w.reapply = enable
+	w.mu.Unlock()
+}
+
+func (w *WPA) interfaceAdded(path dbus.ObjectPath) {
+	info, err := GetInterfaceInfo(w.bus, path)
+	if err != nil || w.GetInterface(info.Ifname) != nil {
+		// attached interfaces are re-created by serviceUp
+		return
+	}
+	iface := NewWPAInterface(w.ctx, w.bus)
+	iface.ifname = info.Ifname
+	iface.setPath(path)
+	iface.opts = InterfaceOptions{Ifname: info.Ifname, Driver: info.Driver, BridgeIfname: info.BridgeIfname}
+	w.attach(iface)
+}
+
+func (w *WPA) serviceUp() {
+	w.mu.Lock()
+	reapply := w.reapply
+	ifaces := make([]*WPAInterface, 0, len(w.ifaces))
+	for _, iface := range w.ifaces {
+		ifaces = append(ifaces, iface)
+	}
+	w.mu.Unlock()
+
+	event := ServiceUp{}
+	for _, iface := range ifaces {
+		if err := iface.recreate(reapply); err != nil {
+			w.detach(iface.ifname)
+			continue
+		}
+		if w.profiles != nil {
+			if err := iface.SyncProfiles(w.profiles, w.conflict); err != nil {
+				w.detach(iface.ifname)
+				continue
+			}
+		}
+		event.Interfaces = append(event.Interfaces, iface.ifname)
+	}
+	w.bus.emit(event)
+}
+
+// reconnect retries until the bus is back, then checks whether
+// wpa_supplicant is already there again.
+func (w *WPA) reconnect() bool {
+	if !w.bus.CanReconnect() {
+		return false
+	}
+	for {
+		if err := w.bus.Reconnect(); err == nil {
+			break
+		}
+		select {
+		case <-time.After(reconnectInterval):
+		case <-w.ctx.Done():
+			return false
+		}
+	}
+	if up, err := w.bus.HasOwner(); err == nil && up {
+		w.serviceUp()
+	}
+	return true
+}
+
+// watcher attaches and detaches interfaces which come and go in
+// wpa_supplicant (e.g. USB Wi-Fi dongles) and restores them when
+// wpa_supplicant or dbus-daemon restarts.
+func (w *WPA) watcher(signal chan *dbus.Signal) {
+	defer w.bus.Unsubscribe(signal)
+	for {
+		select {
+		case event := <-signal:
+			if len(event.Body) == 0 {
+				continue
+			}
+			switch event.Name {
+			case SignalInterfaceAdded:
+				if path, ok := event.Body[0].(dbus.ObjectPath); ok {
+					w.interfaceAdded(path)
+				}
+			case SignalInterfaceRemoved:
+				if path, ok := event.Body[0].(dbus.ObjectPath); ok {
+					if ifname, found := w.ifnameByPath(path); found {
+						w.detach(ifname)
+					}
+				}
+			case SignalNameOwnerChanged:
+				if len(event.Body) < 3 || event.Body[0] != WPAServiceName {
+					continue
+				}
+				if isServiceUp(event) {
+					w.serviceUp()
+				} else {
+					w.bus.emit(ServiceDown{Reason: "wpa_supplicant left the bus"})
+				}
+			}
+		case <-w.bus.Done():
+			if w.ctx.Err() != nil {
+				return
+			}
+			w.bus.emit(ServiceDown{Reason: "bus connection lost"})
+			if !w.reconnect() {
+				return
+			}
+		case <-w.ctx.Done():
+			return
+		}
 	}
 }
 
@@ -68,6 +345,22 @@ func (w *WPA) GetEventSignal() <-chan *dbus.Signal {
 	return w.bus.GetSignal()
 }
 
+// GetEvents returns events derived by wpac, e.g. Roamed
+func (w *WPA) GetEvents() <-chan WPAEvent {
+	return w.bus.GetEvents()
+}
+
+// SubscribeEvents returns a dedicated event channel, e.g. for exporters
+// which must not compete with GetEvents readers.
+func (w *WPA) SubscribeEvents() <-chan WPAEvent {
+	return w.bus.SubscribeEvents()
+}
+
+func (w *WPA) UnsubscribeEvents(ch <-chan WPAEvent) {
+	w.bus.UnsubscribeEvents(ch)
+}
+
 func (w *WPA) Close() {
+	w.cancel()
 	w.bus.Close()
 }
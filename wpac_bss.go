@@ -21,10 +21,11 @@ type BSSWPA2 struct {
 
 // WPABSS ...
 type WPABSS struct {
-	busObject dbus.BusObject `json:"busObj"`
+	busObject dbus.BusObject `json:"-"`
 	BSSID     string         `json:"bssid"`
 	SSID      string         `json:"ssid"`
-	PSK       string         `json:"psk"`
+	PSK       Secret         `json:"psk"`
+	PSKRef    string         `json:"psk_ref,omitempty"`
 	WPA       *BSSWPA        `json:"wpa"`
 	WPA2      *BSSWPA2       `json:"wpa2"`
 	WPS       string         `json:"wps"`
@@ -34,11 +35,13 @@ type WPABSS struct {
 	Mode      string         `json:"mode"`
 	Privacy   bool           `json:"privacy"`
 	Priority  int            `json:"priority"`
+
+	OWETransition *OWETransition `json:"owe_transition,omitempty"`
 }
 
 // NewBSS ...
 func NewBSS(bus *WPADBus, objPath dbus.ObjectPath) WPABSS {
-	obj := bus.Connection.Object("fi.w1.wpa_supplicant1", objPath)
+	obj := bus.object(objPath)
 	bss := WPABSS{busObject: obj, WPA: &BSSWPA{}, WPA2: &BSSWPA2{}}
 	bss.readWPA()
 	bss.readRSN()
@@ -49,6 +52,7 @@ func NewBSS(bus *WPADBus, objPath dbus.ObjectPath) WPABSS {
 	bss.readMode()
 	bss.readPrivacy()
 	bss.readFrequency()
+	bss.readIEs()
 	return bss
 }
 
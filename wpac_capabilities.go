@@ -0,0 +1,197 @@
+package wpac
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// InterfaceCapabilities Decoded fi.w1.wpa_supplicant1.Interface.Capabilities
+type InterfaceCapabilities struct {
+	KeyMgmt     []string `json:"key_mgmt"`
+	Pairwise    []string `json:"pairwise"`
+	Group       []string `json:"group"`
+	Protocol    []string `json:"protocol"`
+	AuthAlg     []string `json:"auth_alg"`
+	Scan        []string `json:"scan"`
+	Modes       []string `json:"modes"`
+	MaxScanSSID int32    `json:"max_scan_ssid"`
+}
+
+// ProfileError A network profile value the interface can't handle
+type ProfileError struct {
+	Field     string
+	Value     string
+	Supported []string
+}
+
+func (e *ProfileError) Error() string {
+	if len(e.Supported) == 0 {
+		return fmt.Sprintf("%s %s is not supported by this device", e.Field, e.Value)
+	}
+	return fmt.Sprintf("%s %s is not supported by this device (supported: %s)",
+		e.Field, e.Value, strings.Join(e.Supported, ", "))
+}
+
+// keyMgmtCapabilities maps network key_mgmt values to Capabilities KeyMgmt names
+var keyMgmtCapabilities = map[string]string{
+	"NONE":                "none",
+	"IEEE8021X":           "ieee8021x",
+	"WPA-EAP":             "wpa-eap",
+	"WPA-PSK":             "wpa-psk",
+	"WPA-EAP-SHA256":      "wpa-eap-sha256",
+	"WPA-PSK-SHA256":      "wpa-psk-sha256",
+	"FT-EAP":              "wpa-ft-eap",
+	"FT-PSK":              "wpa-ft-psk",
+	"FT-SAE":              "ft-sae",
+	"SAE":                 "sae",
+	"OWE":                 "owe",
+	"WPA-EAP-SUITE-B":     "wpa-eap-suite-b",
+	"WPA-EAP-SUITE-B-192": "wpa-eap-suite-b-192",
+	"WPS":                 "wps",
+}
+
+// networkModes maps network mode values to Capabilities Modes names
+var networkModes = map[string]string{
+	"0": "infrastructure",
+	"1": "ad-hoc",
+	"2": "ap",
+	"5": "mesh",
+}
+
+func variantString(v dbus.Variant) string {
+	switch value := v.Value().(type) {
+	case string:
+		return strings.Trim(value, `"`)
+	case []byte:
+		return string(value)
+	}
+	return fmt.Sprint(v.Value())
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkAny succeeds if one of the space separated values is supported, like
+// wpa_supplicant which picks any usable entry, e.g. key_mgmt="WPA-PSK SAE".
+func checkAny(field, value string, supported []string, names map[string]string) error {
+	for _, v := range strings.Fields(value) {
+		name := strings.ToLower(v)
+		if names != nil {
+			if n, found := names[strings.ToUpper(v)]; found {
+				name = n
+			}
+		}
+		if contains(supported, name) {
+			return nil
+		}
+	}
+	return &ProfileError{Field: field, Value: value, Supported: supported}
+}
+
+// ValidateProfile checks a network profile against the interface capabilities
+// before it's handed to AddNetwork. Capabilities wpa_supplicant didn't report
+// are not checked.
+func ValidateProfile(caps InterfaceCapabilities, profile map[string]dbus.Variant) error {
+	checks := []struct {
+		field     string
+		supported []string
+		names     map[string]string
+	}{
+		{WPANetworkKeyMgmt, caps.KeyMgmt, keyMgmtCapabilities},
+		{WPANetworkPairWise, caps.Pairwise, nil},
+		{WPANetworkGroup, caps.Group, nil},
+		{WPANetworkProto, caps.Protocol, nil},
+		{"auth_alg", caps.AuthAlg, nil},
+		{WPANetworkMode, caps.Modes, networkModes},
+	}
+	for _, c := range checks {
+		v, found := profile[c.field]
+		if !found || len(c.supported) == 0 {
+			continue
+		}
+		value := variantString(v)
+		if c.field == WPANetworkProto {
+			// WPA2 is an alias of RSN
+			value = strings.Replace(strings.ToUpper(value), "WPA2", "RSN", -1)
+		}
+		if err := checkAny(c.field, value, c.supported, c.names); err != nil {
+			return err
+		}
+	}
+	if v, found := profile["scan_ssid"]; found && variantString(v) == "1" && len(caps.Scan) > 0 {
+		if !contains(caps.Scan, "ssid") {
+			return &ProfileError{Field: "scan_ssid", Value: "1", Supported: caps.Scan}
+		}
+	}
+	return nil
+}
+
+// ValidateProfile checks a network profile against the capabilities of this interface
+func (self *WPAInterface) ValidateProfile(profile map[string]dbus.Variant) error {
+	caps, err := self.Capabilities()
+	if err != nil {
+		return err
+	}
+	return ValidateProfile(caps, profile)
+}
+
+// Capabilities What the driver and wpa_supplicant build support on this interface,
+// e.g. KeyMgmt ["none", "ieee8021x", "wpa-eap", "wpa-psk", "sae", ...].
+func (self *WPAInterface) Capabilities() (InterfaceCapabilities, error) {
+	caps := InterfaceCapabilities{}
+	obj := self.bus.object(self.Path())
+	prop, err := obj.GetProperty("fi.w1.wpa_supplicant1.Interface.Capabilities")
+	if err != nil {
+		return caps, err
+	}
+	dict, ok := prop.Value().(map[string]dbus.Variant)
+	if !ok {
+		return caps, errors.New("invalid capabilities")
+	}
+	for k, v := range dict {
+		switch k {
+		case "KeyMgmt":
+			caps.KeyMgmt, _ = v.Value().([]string)
+		case "Pairwise":
+			caps.Pairwise, _ = v.Value().([]string)
+		case "Group":
+			caps.Group, _ = v.Value().([]string)
+		case "Protocol":
+			caps.Protocol, _ = v.Value().([]string)
+		case "AuthAlg":
+			caps.AuthAlg, _ = v.Value().([]string)
+		case "Scan":
+			caps.Scan, _ = v.Value().([]string)
+		case "Modes":
+			caps.Modes, _ = v.Value().([]string)
+		case "MaxScanSSID":
+			caps.MaxScanSSID = int32(variantInt64(v))
+		}
+	}
+	return caps, nil
+}
+
+// Country ISO/IEC alpha2 country code of the interface.
+func (self *WPAInterface) Country() (string, error) {
+	obj := self.bus.object(self.Path())
+	prop, err := obj.GetProperty("fi.w1.wpa_supplicant1.Interface.Country")
+	if err != nil {
+		return "", err
+	}
+	return prop.Value().(string), nil
+}
+
+func (self *WPAInterface) SetCountry(country string) error {
+	value := dbus.MakeVariant(country)
+	obj := self.bus.object(self.Path())
+	return obj.SetProperty("fi.w1.wpa_supplicant1.Interface.Country", value)
+}
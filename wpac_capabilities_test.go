@@ -0,0 +1,82 @@
+package wpac
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/godbus/dbus/v5"
+)
+
+func TestCheckAny(t *testing.T) {
+	supported := []string{"none", "wpa-psk", "wpa-ft-psk", "sae", "wpa-eap-suite-b-192"}
+	tests := []struct {
+		value string
+		ok    bool
+	}{
+		{"WPA-PSK", true},
+		{"FT-PSK WPA-PSK", true},
+		{"SAE FT-SAE", true},
+		{"WPA-EAP-SUITE-B-192", true},
+		{"WPA-EAP-SUITE-B", false},
+		{"FT-SAE", false},
+		{"OWE", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		err := checkAny(WPANetworkKeyMgmt, tt.value, supported, keyMgmtCapabilities)
+		if (err == nil) != tt.ok {
+			t.Errorf("checkAny(%q) = %v, want ok %v", tt.value, err, tt.ok)
+		}
+	}
+}
+
+func TestValidateProfile(t *testing.T) {
+	caps := InterfaceCapabilities{
+		KeyMgmt:  []string{"none", "ieee8021x", "wpa-eap", "wpa-psk", "wpa-eap-suite-b", "wpa-eap-suite-b-192"},
+		Pairwise: []string{"ccmp", "tkip"},
+		Protocol: []string{"rsn", "wpa"},
+		Modes:    []string{"infrastructure"},
+		Scan:     []string{"active", "passive"},
+	}
+	tests := []struct {
+		name    string
+		profile map[string]dbus.Variant
+		field   string
+	}{
+		{"wpa2 psk", map[string]dbus.Variant{
+			WPANetworkKeyMgmt: dbus.MakeVariant("WPA-PSK"),
+			WPANetworkProto:   dbus.MakeVariant("WPA2"),
+		}, ""},
+		{"suite-b", map[string]dbus.Variant{
+			WPANetworkKeyMgmt: dbus.MakeVariant("WPA-EAP-SUITE-B"),
+		}, ""},
+		{"suite-b-192", map[string]dbus.Variant{
+			WPANetworkKeyMgmt: dbus.MakeVariant("WPA-EAP-SUITE-B-192"),
+		}, ""},
+		{"sae unsupported", map[string]dbus.Variant{
+			WPANetworkKeyMgmt: dbus.MakeVariant("SAE"),
+		}, WPANetworkKeyMgmt},
+		{"gcmp unsupported", map[string]dbus.Variant{
+			WPANetworkPairWise: dbus.MakeVariant("GCMP"),
+		}, WPANetworkPairWise},
+		{"ap mode unsupported", map[string]dbus.Variant{
+			WPANetworkMode: dbus.MakeVariant(int32(2)),
+		}, WPANetworkMode},
+		{"hidden without ssid scan", map[string]dbus.Variant{
+			"scan_ssid": dbus.MakeVariant(int32(1)),
+		}, "scan_ssid"},
+	}
+	for _, tt := range tests {
+		err := ValidateProfile(caps, tt.profile)
+		if tt.field == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error %v", tt.name, err)
+			}
+			continue
+		}
+		perr := &ProfileError{}
+		if !errors.As(err, &perr) || perr.Field != tt.field {
+			t.Errorf("%s: error %v, want a ProfileError of %s", tt.name, err, tt.field)
+		}
+	}
+}
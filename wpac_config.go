@@ -1,9 +1,38 @@
 package wpac
 
 import (
+	"fmt"
+
 	"github.com/godbus/dbus/v5"
 )
 
+const (
+	BGScanSimple = "simple"
+	BGScanLearn  = "learn"
+)
+
+// BGScan Background scan parameters of a network profile.
+// simple: <short interval>:<signal threshold>:<long interval>
+// learn:  <short interval>:<signal threshold>:<long interval>:<database>
+type BGScan struct {
+	Module          string
+	ShortInterval   int
+	SignalThreshold int
+	LongInterval    int
+	Database        string
+}
+
+func (bg BGScan) String() string {
+	if bg.Module == "" {
+		return ""
+	}
+	s := fmt.Sprintf("%s:%d:%d:%d", bg.Module, bg.ShortInterval, bg.SignalThreshold, bg.LongInterval)
+	if bg.Module == BGScanLearn && bg.Database != "" {
+		s += ":" + bg.Database
+	}
+	return s
+}
+
 type WPASupplicantConfig struct{}
 
 var instance *WPASupplicantConfig
@@ -15,27 +44,45 @@ func WPAConfig() *WPASupplicantConfig {
 	return instance
 }
 
+// GetWPAPSK WPA-PSK with TKIP, fails with ErrInvalidPassphrase if the
+// passphrase can't be turned into a PSK
+func (config *WPASupplicantConfig) GetWPAPSK(bss WPABSS) (map[string]dbus.Variant, error) {
+	template, err := config.pskTemplate(bss, "WPA", "TKIP")
+	if err != nil {
+		return nil, err
+	}
+	return template, nil
+}
+
+// GetWPA2PSK WPA2-PSK with CCMP, fails with ErrInvalidPassphrase if the
+// passphrase can't be turned into a PSK
+func (config *WPASupplicantConfig) GetWPA2PSK(bss WPABSS) (map[string]dbus.Variant, error) {
+	template, err := config.pskTemplate(bss, "RSN", "CCMP")
+	if err != nil {
+		return nil, err
+	}
+	return template, nil
+}
+
+// GetWPAWPA2PSK WPA/WPA2 mixed mode with CCMP, fails with ErrInvalidPassphrase
+// if the passphrase can't be turned into a PSK
+func (config *WPASupplicantConfig) GetWPAWPA2PSK(bss WPABSS) (map[string]dbus.Variant, error) {
+	template, err := config.pskTemplate(bss, "WPA RSN", "CCMP")
+	if err != nil {
+		return nil, err
+	}
+	return template, nil
+}
+
+// Deprecated: use GetWPAPSK, GetWPA leaves psk unset on an invalid passphrase.
 func (config *WPASupplicantConfig) GetWPA(bss WPABSS) map[string]dbus.Variant {
-	template := make(map[string]dbus.Variant)
-	template["bssid"] = dbus.MakeVariant(bss.BSSID)
-	template["ssid"] = dbus.MakeVariant(bss.SSID)
-	template["psk"] = dbus.MakeVariant(bss.PSK)
-	template["proto"] = dbus.MakeVariant("WPA")
-	template["pairwise"] = dbus.MakeVariant("TKIP")
-	template["group"] = dbus.MakeVariant("TKIP")
-	template["key_mgmt"] = dbus.MakeVariant("WPA-PSK")
+	template, _ := config.pskTemplate(bss, "WPA", "TKIP")
 	return template
 }
 
+// Deprecated: use GetWPA2PSK, GetWPA2 leaves psk unset on an invalid passphrase.
 func (config *WPASupplicantConfig) GetWPA2(bss WPABSS) map[string]dbus.Variant {
-	template := make(map[string]dbus.Variant)
-	template["bssid"] = dbus.MakeVariant(bss.BSSID)
-	template["ssid"] = dbus.MakeVariant(bss.SSID)
-	template["psk"] = dbus.MakeVariant(bss.PSK)
-	template["proto"] = dbus.MakeVariant("RSN")
-	template["pairwise"] = dbus.MakeVariant("CCMP")
-	template["group"] = dbus.MakeVariant("CCMP")
-	template["key_mgmt"] = dbus.MakeVariant("WPA-PSK")
+	template, _ := config.pskTemplate(bss, "RSN", "CCMP")
 	return template
 }
 
@@ -46,14 +93,119 @@ func (config *WPASupplicantConfig) GetWPANone(bss WPABSS) map[string]dbus.Varian
 	return template
 }
 
+// Deprecated: use GetWPAWPA2PSK, GetWPAWPA2 leaves psk unset on an invalid passphrase.
 func (config *WPASupplicantConfig) GetWPAWPA2(bss WPABSS) map[string]dbus.Variant {
+	template, _ := config.pskTemplate(bss, "WPA RSN", "CCMP")
+	return template
+}
+
+// pskTemplate returns the template without psk along with the error of setPSK,
+// the deprecated templates keep it that way
+func (config *WPASupplicantConfig) pskTemplate(bss WPABSS, proto, cipher string) (map[string]dbus.Variant, error) {
 	template := make(map[string]dbus.Variant)
 	template["bssid"] = dbus.MakeVariant(bss.BSSID)
 	template["ssid"] = dbus.MakeVariant(bss.SSID)
-	template["psk"] = dbus.MakeVariant(bss.PSK)
-	template["proto"] = dbus.MakeVariant("WPA RSN")
+	err := config.setPSK(template, bss)
+	template["proto"] = dbus.MakeVariant(proto)
+	template["pairwise"] = dbus.MakeVariant(cipher)
+	template["group"] = dbus.MakeVariant(cipher)
+	template["key_mgmt"] = dbus.MakeVariant("WPA-PSK")
+	return template, err
+}
+
+// setPSK hands the derived PSK to wpa_supplicant as a byte array, which it
+// stores as raw hex, so the passphrase never leaves the process. An invalid
+// passphrase fails with ErrInvalidPassphrase, a PSK profile without key is
+// useless. PSKRef takes precedence and is resolved when the network is added.
+func (config *WPASupplicantConfig) setPSK(template map[string]dbus.Variant, bss WPABSS) error {
+	if bss.PSKRef != "" {
+		template["psk"] = dbus.MakeVariant(SecretRef(bss.PSKRef))
+		return nil
+	}
+	psk, err := DerivePSK(bss.PSK, bss.SSID)
+	if err != nil {
+		return err
+	}
+	template["psk"] = dbus.MakeVariant(psk)
+	return nil
+}
+
+// SetBGScan adds bgscan to a network template, an empty BGScan disables it
+func (config *WPASupplicantConfig) SetBGScan(template map[string]dbus.Variant, bg BGScan) map[string]dbus.Variant {
+	template["bgscan"] = dbus.MakeVariant(bg.String())
+	return template
+}
+
+const (
+	EAPMethodPEAP = "PEAP"
+	EAPMethodTTLS = "TTLS"
+	EAPMethodTLS  = "TLS"
+	EAPMethodMD5  = "MD5"
+)
+
+// EAPConfig EAP parameters of an 802.1X network, SSID is ignored for wired ports.
+// The *Ref fields take precedence over the secrets and are resolved through
+// the SecretStore when the network is added.
+type EAPConfig struct {
+	SSID               string
+	Method             string
+	Identity           string
+	AnonymousIdentity  string
+	Password           Secret
+	CACert             string
+	ClientCert         string
+	PrivateKey         string
+	PrivateKeyPassword Secret
+	Phase2             string
+
+	PasswordRef           string
+	PrivateKeyPasswordRef string
+}
+
+func (config *WPASupplicantConfig) setEAP(template map[string]dbus.Variant, eap EAPConfig) {
+	template["eap"] = dbus.MakeVariant(eap.Method)
+	params := map[string]string{
+		"identity":           eap.Identity,
+		"anonymous_identity": eap.AnonymousIdentity,
+		"password":           eap.Password.Reveal(),
+		"ca_cert":            eap.CACert,
+		"client_cert":        eap.ClientCert,
+		"private_key":        eap.PrivateKey,
+		"private_key_passwd": eap.PrivateKeyPassword.Reveal(),
+		"phase2":             eap.Phase2,
+	}
+	for k, v := range params {
+		if v != "" {
+			template[k] = dbus.MakeVariant(v)
+		}
+	}
+	refs := map[string]string{
+		"password":           eap.PasswordRef,
+		"private_key_passwd": eap.PrivateKeyPasswordRef,
+	}
+	for k, ref := range refs {
+		if ref != "" {
+			template[k] = dbus.MakeVariant(SecretRef(ref))
+		}
+	}
+}
+
+// GetWired8021X Network of a wired port authenticated by IEEE 802.1X (driver "wired")
+func (config *WPASupplicantConfig) GetWired8021X(eap EAPConfig) map[string]dbus.Variant {
+	template := make(map[string]dbus.Variant)
+	template["key_mgmt"] = dbus.MakeVariant("IEEE8021X")
+	template["eapol_flags"] = dbus.MakeVariant(int32(0))
+	config.setEAP(template, eap)
+	return template
+}
+
+func (config *WPASupplicantConfig) GetWPA2EAP(eap EAPConfig) map[string]dbus.Variant {
+	template := make(map[string]dbus.Variant)
+	template["ssid"] = dbus.MakeVariant(eap.SSID)
+	template["proto"] = dbus.MakeVariant("RSN")
 	template["pairwise"] = dbus.MakeVariant("CCMP")
 	template["group"] = dbus.MakeVariant("CCMP")
-	template["key_mgmt"] = dbus.MakeVariant("WPA-PSK")
+	template["key_mgmt"] = dbus.MakeVariant("WPA-EAP")
+	config.setEAP(template, eap)
 	return template
 }
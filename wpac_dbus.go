@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sync"
 
 	"github.com/godbus/dbus/v5"
 )
@@ -14,36 +15,157 @@ type DBusProp struct {
 	Value     string
 }
 
+const (
+	WPAServiceName = "fi.w1.wpa_supplicant1"
+
+	nameOwnerChangedMatch = "type='signal',sender='org.freedesktop.DBus',interface='org.freedesktop.DBus'," +
+		"member='NameOwnerChanged',arg0='" + WPAServiceName + "'"
+)
+
+// WPADBus Connection and Object are replaced by Reconnect, read them through
+// Conn and BusObject while the bus is in use.
 type WPADBus struct {
+	mu         sync.RWMutex
 	Connection *dbus.Conn
 	Object     dbus.BusObject
 	Signal     *WPASignal
+	events     chan WPAEvent
+	hub        *eventHub
+	dial       func() (*dbus.Conn, error)
+	secrets    SecretStore
+	// owned connections are closed by Close, shared or given ones are not
+	owned bool
+}
+
+// dialSystemBus opens a private system bus connection, the shared one can't
+// be replaced once dbus-daemon drops it.
+func dialSystemBus() (*dbus.Conn, error) {
+	return dialBus(func() (*dbus.Conn, error) { return dbus.SystemBusPrivate() })
 }
 
+func dialBus(open func() (*dbus.Conn, error)) (*dbus.Conn, error) {
+	conn, err := open()
+	if err != nil {
+		return nil, err
+	}
+	if err := conn.Auth(nil); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if err := conn.Hello(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// NewWpaDBus uses the system bus connection shared by the process
 func NewWpaDBus(ctx context.Context) (*WPADBus, error) {
-	var wdbus *WPADBus
-	if conn, err := dbus.SystemBus(); err == nil {
-		if obj := conn.Object("fi.w1.wpa_supplicant1", "/fi/w1/wpa_supplicant1"); obj != nil {
-			wdbus = &WPADBus{
-				Connection: conn,
-				Object:     obj,
-				Signal:     NewWPASignal(conn),
-			}
-			if err := wdbus.AddSignalObserver("fi.w1.wpa_supplicant1", "/fi/w1/wpa_supplicant1"); err != nil {
-				wdbus.Close()
-				return nil, fmt.Errorf("create dbus signal hook failed (%s)", err.Error())
-			}
-		} else {
+	conn, err := dbus.SystemBus()
+	if err != nil {
+		return nil, fmt.Errorf("%w (%s)", ErrBusUnavailable, err.Error())
+	}
+	return NewWpaDBusWithConn(conn, false, dialSystemBus)
+}
+
+// NewWpaDBusWithConn wraps conn, which is closed by Close only if owned.
+// dial re-establishes the connection when the bus goes away, nil disables it.
+func NewWpaDBusWithConn(conn *dbus.Conn, owned bool, dial func() (*dbus.Conn, error)) (*WPADBus, error) {
+	obj := conn.Object("fi.w1.wpa_supplicant1", "/fi/w1/wpa_supplicant1")
+	if obj == nil {
+		if owned {
 			conn.Close()
-			return nil, errors.New("Can't create WPA object")
 		}
+		return nil, errors.New("Can't create WPA object")
+	}
+	wdbus := &WPADBus{
+		Connection: conn,
+		Object:     obj,
+		Signal:     NewWPASignal(conn),
+		hub:        newEventHub(),
+		dial:       dial,
+		owned:      owned,
+	}
+	wdbus.events = wdbus.hub.subscribe()
+	if err := wdbus.AddSignalObserver("fi.w1.wpa_supplicant1", "/fi/w1/wpa_supplicant1"); err != nil {
+		wdbus.Close()
+		return nil, fmt.Errorf("create dbus signal hook failed (%s)", err.Error())
+	}
+	if err := wdbus.Signal.AddMatch(nameOwnerChangedMatch); err != nil {
+		wdbus.Close()
+		return nil, fmt.Errorf("create dbus signal hook failed (%s)", err.Error())
 	}
 	return wdbus, nil
 }
 
+// Conn the current bus connection
+func (self *WPADBus) Conn() *dbus.Conn {
+	self.mu.RLock()
+	defer self.mu.RUnlock()
+	return self.Connection
+}
+
+// BusObject the wpa_supplicant object of the current connection
+func (self *WPADBus) BusObject() dbus.BusObject {
+	self.mu.RLock()
+	defer self.mu.RUnlock()
+	return self.Object
+}
+
+// object a wpa_supplicant object at path on the current connection
+func (self *WPADBus) object(path dbus.ObjectPath) dbus.BusObject {
+	return self.Conn().Object("fi.w1.wpa_supplicant1", path)
+}
+
+// Done is closed when the bus connection is lost
+func (self *WPADBus) Done() <-chan struct{} {
+	return self.Conn().Context().Done()
+}
+
+// HasOwner reports whether wpa_supplicant is currently on the bus
+func (self *WPADBus) HasOwner() (bool, error) {
+	var has bool
+	err := self.Conn().BusObject().Call("org.freedesktop.DBus.NameHasOwner", 0, WPAServiceName).Store(&has)
+	return has, err
+}
+
+// StartService asks dbus-daemon to activate wpa_supplicant
+func (self *WPADBus) StartService() error {
+	var reply uint32
+	return self.Conn().BusObject().Call("org.freedesktop.DBus.StartServiceByName", 0, WPAServiceName, uint32(0)).Store(&reply)
+}
+
+// Reconnect dials the bus again and restores every signal hook
+func (self *WPADBus) Reconnect() error {
+	if !self.CanReconnect() {
+		return errors.New("bus connection is not owned by wpac")
+	}
+	conn, err := self.dial()
+	if err != nil {
+		return err
+	}
+	self.mu.Lock()
+	old, owned := self.Connection, self.owned
+	self.Connection = conn
+	self.Object = conn.Object("fi.w1.wpa_supplicant1", "/fi/w1/wpa_supplicant1")
+	self.owned = true
+	self.mu.Unlock()
+	if err := self.Signal.Rebind(conn); err != nil {
+		return err
+	}
+	if owned {
+		old.Close()
+	}
+	return nil
+}
+
+func (self *WPADBus) CanReconnect() bool {
+	return self.dial != nil
+}
+
 func (self *WPADBus) SetProperty(prop DBusProp) error {
 	value := dbus.MakeVariant(prop.Value)
-	call := self.Object.Call("org.freedesktop.DBus.Properties.Set", 0, prop.Interface, prop.Name, value)
+	call := self.BusObject().Call("org.freedesktop.DBus.Properties.Set", 0, prop.Interface, prop.Name, value)
 	if call.Err != nil {
 		return call.Err
 	}
@@ -51,24 +173,62 @@ func (self *WPADBus) SetProperty(prop DBusProp) error {
 }
 
 func (self *WPADBus) GetProperty(name string) (value interface{}, e error) {
-	variant, err := self.Object.GetProperty(name)
+	variant, err := self.BusObject().GetProperty(name)
 	if err != nil {
 		return nil, err
 	}
 	return variant.Value(), nil
 }
 
+// SetSecretStore replaces the store which resolves SecretRef values of profiles
+func (self *WPADBus) SetSecretStore(store SecretStore) {
+	self.mu.Lock()
+	self.secrets = store
+	self.mu.Unlock()
+}
+
+func (self *WPADBus) secretStore() SecretStore {
+	self.mu.RLock()
+	defer self.mu.RUnlock()
+	return self.secrets
+}
+
 func (self *WPADBus) GetSignal() chan *dbus.Signal {
 	return self.Signal.Get()
 }
 
+func (self *WPADBus) Subscribe() chan *dbus.Signal {
+	return self.Signal.Subscribe()
+}
+
+func (self *WPADBus) Unsubscribe(ch chan *dbus.Signal) {
+	self.Signal.Unsubscribe(ch)
+}
+
+func (self *WPADBus) GetEvents() chan WPAEvent {
+	return self.events
+}
+
+func (self *WPADBus) SubscribeEvents() chan WPAEvent {
+	return self.hub.subscribe()
+}
+
+func (self *WPADBus) UnsubscribeEvents(ch <-chan WPAEvent) {
+	self.hub.unsubscribe(ch)
+}
+
+// emit never blocks, events are dropped for subscribers which don't drain their channel
+func (self *WPADBus) emit(event WPAEvent) {
+	self.hub.emit(event)
+}
+
 func (self *WPADBus) AddSignalObserver(dbusInterface string, object dbus.ObjectPath) error {
 	return self.Signal.AddObserver(dbusInterface, object)
 }
 
 func (self *WPADBus) Call(path string) (dbus.ObjectPath, error) {
 	var objectPath dbus.ObjectPath
-	call := self.Object.Call(path, 0)
+	call := self.BusObject().Call(path, 0)
 	if call.Err != nil {
 		return "", call.Err
 	}
@@ -80,7 +240,7 @@ func (self *WPADBus) Call(path string) (dbus.ObjectPath, error) {
 
 func (self *WPADBus) CallWithPath(path string, args dbus.ObjectPath) (dbus.ObjectPath, error) {
 	var objectPath dbus.ObjectPath
-	call := self.Object.Call(path, 0, args)
+	call := self.BusObject().Call(path, 0, args)
 	if call.Err != nil {
 		return "", call.Err
 	}
@@ -92,7 +252,7 @@ func (self *WPADBus) CallWithPath(path string, args dbus.ObjectPath) (dbus.Objec
 
 func (self *WPADBus) CallWithString(path string, args string) (dbus.ObjectPath, error) {
 	var objectPath dbus.ObjectPath
-	call := self.Object.Call(path, 0, args)
+	call := self.BusObject().Call(path, 0, args)
 	if call.Err != nil {
 		return "", call.Err
 	}
@@ -104,7 +264,7 @@ func (self *WPADBus) CallWithString(path string, args string) (dbus.ObjectPath,
 
 func (self *WPADBus) CallWithVariant(path string, args map[string]dbus.Variant) (dbus.ObjectPath, error) {
 	var objectPath dbus.ObjectPath
-	call := self.Object.Call(path, 0, args)
+	call := self.BusObject().Call(path, 0, args)
 	if call.Err != nil {
 		return "", call.Err
 	}
@@ -124,5 +284,10 @@ func (self *WPADBus) MakeVariant(variant map[string]interface{}) map[string]dbus
 
 func (self *WPADBus) Close() {
 	self.Signal.Close()
-	self.Connection.Close()
+	self.mu.RLock()
+	conn, owned := self.Connection, self.owned
+	self.mu.RUnlock()
+	if owned {
+		conn.Close()
+	}
 }
@@ -0,0 +1,278 @@
+package wpac
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// privateBus starts a dbus-daemon for the test, without wpa_supplicant on it
+func privateBus(t *testing.T) string {
+	daemon, err := exec.LookPath("dbus-daemon")
+	if err != nil {
+		t.Skip("dbus-daemon not found")
+	}
+	cmd := exec.Command(daemon, "--session", "--nofork", "--print-address")
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Skipf("dbus-daemon failed to start (%s)", err.Error())
+	}
+	t.Cleanup(func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	})
+	addr, err := bufio.NewReader(stdout).ReadString('\n')
+	if err != nil {
+		t.Fatalf("no bus address (%s)", err.Error())
+	}
+	return strings.TrimSpace(addr)
+}
+
+// fakeSupplicant answers the network calls of one interface, enough to drive recreate
+type fakeSupplicant struct {
+	mu       sync.Mutex
+	next     int
+	networks []dbus.ObjectPath
+}
+
+const fakeIface = dbus.ObjectPath("/fi/w1/wpa_supplicant1/Interfaces/0")
+
+func (f *fakeSupplicant) GetInterface(ifname string) (dbus.ObjectPath, *dbus.Error) {
+	return fakeIface, nil
+}
+
+func (f *fakeSupplicant) AddNetwork(args map[string]dbus.Variant) (dbus.ObjectPath, *dbus.Error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	path := dbus.ObjectPath(fmt.Sprintf("%s/Networks/%d", fakeIface, f.next))
+	f.next++
+	f.networks = append(f.networks, path)
+	return path, nil
+}
+
+func (f *fakeSupplicant) RemoveNetwork(path dbus.ObjectPath) *dbus.Error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i, p := range f.networks {
+		if p == path {
+			f.networks = append(f.networks[:i], f.networks[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+func (f *fakeSupplicant) RemoveAllNetworks() *dbus.Error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.networks = nil
+	return nil
+}
+
+func (f *fakeSupplicant) SelectNetwork(path dbus.ObjectPath) *dbus.Error {
+	return nil
+}
+
+// fakeProperties serves Interface.Networks, every other property is unknown
+type fakeProperties struct{ *fakeSupplicant }
+
+func (p fakeProperties) Get(iface, name string) (dbus.Variant, *dbus.Error) {
+	if iface != "fi.w1.wpa_supplicant1.Interface" || name != "Networks" {
+		return dbus.Variant{}, dbus.MakeFailedError(fmt.Errorf("unknown property %s.%s", iface, name))
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return dbus.MakeVariant(append([]dbus.ObjectPath(nil), p.networks...)), nil
+}
+
+// serveFakeSupplicant owns fi.w1.wpa_supplicant1 on the bus at addr
+func serveFakeSupplicant(t *testing.T, addr string) {
+	conn, err := dialBus(func() (*dbus.Conn, error) { return dbus.Dial(addr) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	f := &fakeSupplicant{}
+	conn.Export(f, "/fi/w1/wpa_supplicant1", "fi.w1.wpa_supplicant1")
+	conn.Export(f, fakeIface, "fi.w1.wpa_supplicant1.Interface")
+	conn.Export(fakeProperties{f}, fakeIface, "org.freedesktop.DBus.Properties")
+	if reply, err := conn.RequestName("fi.w1.wpa_supplicant1", dbus.NameFlagDoNotQueue); err != nil || reply != dbus.RequestNameReplyPrimaryOwner {
+		t.Fatalf("fi.w1.wpa_supplicant1 not owned (%v, %v)", reply, err)
+	}
+}
+
+func TestRecreateConcurrentNetworkUse(t *testing.T) {
+	addr := privateBus(t)
+	serveFakeSupplicant(t, addr)
+	dial := func() (*dbus.Conn, error) {
+		return dialBus(func() (*dbus.Conn, error) { return dbus.Dial(addr) })
+	}
+	conn, err := dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+	bus, err := NewWpaDBusWithConn(conn, true, dial)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bus.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	iface := NewWPAInterface(ctx, bus)
+	iface.opts = InterfaceOptions{Ifname: "wlan0"}
+	if err := iface.recreate(false); err != nil {
+		t.Fatal(err)
+	}
+	defer iface.Detach()
+
+	const rounds = 20
+	wg := sync.WaitGroup{}
+	wg.Add(3)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < rounds; i++ {
+			// what serviceUp runs on the watcher goroutine after a restart
+			if err := iface.recreate(true); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < rounds; i++ {
+			network, err := iface.AddNetwork(map[string]dbus.Variant{"ssid": dbus.MakeVariant("test")})
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			// recreate may have renumbered it already, only the map access matters
+			iface.SelectNetwork(network.ID)
+			iface.SetNetworkEnabled(network.ID, true)
+			iface.RemoveNetwork(network.ID)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < rounds; i++ {
+			networks, err := iface.GetNetworks()
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			for id := range networks {
+				delete(networks, id)
+			}
+			if i%5 == 0 {
+				iface.RemoveAllNetwork()
+			}
+		}
+	}()
+	wg.Wait()
+}
+
+func TestReconnectConcurrentUse(t *testing.T) {
+	addr := privateBus(t)
+	dial := func() (*dbus.Conn, error) {
+		return dialBus(func() (*dbus.Conn, error) { return dbus.Dial(addr) })
+	}
+	conn, err := dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+	bus, err := NewWpaDBusWithConn(conn, true, dial)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bus.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	iface := NewWPAInterface(ctx, bus)
+	iface.setPath("/fi/w1/wpa_supplicant1/Interfaces/0")
+	if err := iface.AddEventListener(); err != nil {
+		t.Fatal(err)
+	}
+	defer iface.Detach()
+
+	const rounds = 20
+	wg := sync.WaitGroup{}
+	wg.Add(4)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < rounds; i++ {
+			if err := bus.Reconnect(); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < rounds; i++ {
+			bus.HasOwner()
+			iface.State()
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < rounds; i++ {
+			bus.AddSignalObserver("fi.w1.wpa_supplicant1.BSS", "/fi/w1/wpa_supplicant1/Interfaces/0/BSSs/0")
+			bus.Signal.RemoveObserver("fi.w1.wpa_supplicant1.BSS", "/fi/w1/wpa_supplicant1/Interfaces/0/BSSs/0")
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < rounds; i++ {
+			iface.setPath(dbus.ObjectPath("/fi/w1/wpa_supplicant1/Interfaces/" + string(rune('0'+i%2))))
+			ch := bus.Subscribe()
+			bus.Unsubscribe(ch)
+		}
+	}()
+	wg.Wait()
+
+	if has, err := bus.HasOwner(); err != nil || has {
+		t.Errorf("HasOwner() = %v, %v on a bus without wpa_supplicant", has, err)
+	}
+}
+
+func TestSignalMatchesAreCounted(t *testing.T) {
+	addr := privateBus(t)
+	conn, err := dialBus(func() (*dbus.Conn, error) { return dbus.Dial(addr) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	ws := NewWPASignal(conn)
+	defer ws.Close()
+
+	path := dbus.ObjectPath("/fi/w1/wpa_supplicant1/Interfaces/0")
+	for i := 0; i < 2; i++ {
+		if err := ws.AddObserver("fi.w1.wpa_supplicant1.Interface", path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	match := observerMatch("fi.w1.wpa_supplicant1.Interface", path)
+	if err := ws.RemoveObserver("fi.w1.wpa_supplicant1.Interface", path); err != nil {
+		t.Fatal(err)
+	}
+	if n := ws.matches[match]; n != 1 {
+		t.Fatalf("match count %d after one of two removals, want 1", n)
+	}
+	if err := ws.RemoveObserver("fi.w1.wpa_supplicant1.Interface", path); err != nil {
+		t.Fatal(err)
+	}
+	if _, found := ws.matches[match]; found {
+		t.Fatal("match kept after the last removal")
+	}
+}
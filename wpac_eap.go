@@ -0,0 +1,107 @@
+package wpac
+
+import (
+	"strings"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	EventEAP = "EAP"
+
+	// SignalEAP EAP peer status, e.g. ("method", "PEAP") or ("completion", "success")
+	SignalEAP = "fi.w1.wpa_supplicant1.Interface.EAP"
+)
+
+// EAPStatus is emitted for every EAP signal of the interface
+type EAPStatus struct {
+	Ifname    string `json:"ifname"`
+	Status    string `json:"status"`
+	Parameter string `json:"parameter"`
+}
+
+func (e EAPStatus) EventName() string {
+	return EventEAP
+}
+
+// EAPOLStatus Last known 802.1X authentication state of the interface. Status
+// and Parameter are the last EAP signal, e.g. ("completion", "failure"), AuthMode
+// the CurrentAuthMode of wpa_supplicant, e.g. "EAP-PEAP".
+type EAPOLStatus struct {
+	State      string `json:"state"`
+	AuthMode   string `json:"auth_mode"`
+	Method     string `json:"method"`
+	Completion string `json:"completion"`
+	Status     string `json:"status"`
+	Parameter  string `json:"parameter"`
+}
+
+// InitWiredInterface attaches a wired port with ap_scan=0 so wpa_supplicant
+// starts EAPOL authentication as soon as a network is selected.
+func (w *WPA) InitWiredInterface(ifname string) error {
+	if err := w.InitInterfaceWithOptions(InterfaceOptions{Ifname: ifname, Driver: DriverWired}); err != nil {
+		return err
+	}
+	return w.GetInterface(ifname).SetApScan(0)
+}
+
+// SetApScan 0 for wired drivers, 1 to let wpa_supplicant scan and select APs.
+func (self *WPAInterface) SetApScan(apScan uint32) error {
+	value := dbus.MakeVariant(apScan)
+	obj := self.bus.object(self.Path())
+	return obj.SetProperty("fi.w1.wpa_supplicant1.Interface.ApScan", value)
+}
+
+// EAPLogoff IEEE 802.1X EAPOL state machine logoff.
+func (self *WPAInterface) EAPLogoff() error {
+	obj := self.bus.object(self.Path())
+	if call := obj.Call("fi.w1.wpa_supplicant1.Interface.EAPLogoff", 0); call.Err != nil {
+		return call.Err
+	}
+	return nil
+}
+
+// EAPLogon IEEE 802.1X EAPOL state machine logon.
+func (self *WPAInterface) EAPLogon() error {
+	obj := self.bus.object(self.Path())
+	if call := obj.Call("fi.w1.wpa_supplicant1.Interface.EAPLogon", 0); call.Err != nil {
+		return call.Err
+	}
+	return nil
+}
+
+func (self *WPAInterface) EAPOLStatus() EAPOLStatus {
+	self.mu.Lock()
+	status := self.eapol
+	self.mu.Unlock()
+	status.State = self.State()
+	obj := self.bus.object(self.Path())
+	if prop, err := obj.GetProperty("fi.w1.wpa_supplicant1.Interface.CurrentAuthMode"); err == nil {
+		status.AuthMode, _ = prop.Value().(string)
+	}
+	if status.Method == "" && strings.HasPrefix(status.AuthMode, "EAP-") {
+		// no EAP signal seen yet, e.g. authenticated before wpac attached
+		status.Method = strings.TrimPrefix(status.AuthMode, "EAP-")
+	}
+	return status
+}
+
+func (w *WPAInterface) updateEAP(body []interface{}) {
+	if len(body) < 2 {
+		return
+	}
+	status, _ := body[0].(string)
+	parameter, _ := body[1].(string)
+	w.mu.Lock()
+	switch status {
+	case "started":
+		w.eapol = EAPOLStatus{}
+	case "method":
+		w.eapol.Method = parameter
+	case "completion":
+		w.eapol.Completion = parameter
+	}
+	w.eapol.Status, w.eapol.Parameter = status, parameter
+	w.mu.Unlock()
+	w.bus.emit(EAPStatus{Ifname: w.ifname, Status: status, Parameter: parameter})
+}
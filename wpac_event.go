@@ -0,0 +1,149 @@
+package wpac
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	EventRoamed       = "Roamed"
+	EventStateChanged = "StateChanged"
+	EventConnected    = "Connected"
+	EventDisconnected = "Disconnected"
+	EventAuthFailed   = "AuthFailed"
+	EventScanFinished = "ScanFinished"
+	EventServiceDown  = "ServiceDown"
+	EventServiceUp    = "ServiceUp"
+)
+
+const eventBufferSize = 32
+
+// WPAEvent is an event derived by wpac from wpa_supplicant signals
+type WPAEvent interface {
+	EventName() string
+}
+
+// Roamed is emitted when the CurrentBSS of a connected interface changes
+// without going through a disconnection.
+type Roamed struct {
+	Ifname   string        `json:"ifname"`
+	From     string        `json:"from"`
+	To       string        `json:"to"`
+	Duration time.Duration `json:"duration"`
+}
+
+func (e Roamed) EventName() string {
+	return EventRoamed
+}
+
+type StateChanged struct {
+	Ifname   string `json:"ifname"`
+	State    string `json:"state"`
+	Previous string `json:"previous"`
+}
+
+func (e StateChanged) EventName() string {
+	return EventStateChanged
+}
+
+// Connected is emitted when the interface state reaches "completed"
+type Connected struct {
+	Ifname string `json:"ifname"`
+}
+
+func (e Connected) EventName() string {
+	return EventConnected
+}
+
+// Disconnected carries the DisconnectReason reported by wpa_supplicant
+type Disconnected struct {
+	Ifname string `json:"ifname"`
+	Reason int32  `json:"reason"`
+}
+
+func (e Disconnected) EventName() string {
+	return EventDisconnected
+}
+
+func (e Disconnected) Decode() ReasonCode {
+	return DecodeReason(e.Reason)
+}
+
+// AuthFailed is emitted when the interface falls back to disconnected/scanning
+// in the middle of authentication or key handshakes. Reason is the
+// DisconnectReason reported for the attempt, 0 if there was none.
+type AuthFailed struct {
+	Ifname string `json:"ifname"`
+	State  string `json:"state"`
+	Reason int32  `json:"reason,omitempty"`
+}
+
+func (e AuthFailed) EventName() string {
+	return EventAuthFailed
+}
+
+type ScanFinished struct {
+	Ifname  string `json:"ifname"`
+	Success bool   `json:"success"`
+}
+
+func (e ScanFinished) EventName() string {
+	return EventScanFinished
+}
+
+// ServiceDown is emitted when wpa_supplicant leaves the bus or the bus connection is lost
+type ServiceDown struct {
+	Reason string `json:"reason"`
+}
+
+func (e ServiceDown) EventName() string {
+	return EventServiceDown
+}
+
+// ServiceUp is emitted once wpa_supplicant is back and interfaces are re-created
+type ServiceUp struct {
+	Interfaces []string `json:"interfaces"`
+}
+
+func (e ServiceUp) EventName() string {
+	return EventServiceUp
+}
+
+// eventHub fans out events to every subscriber without blocking the emitter
+type eventHub struct {
+	mu   sync.Mutex
+	subs map[chan WPAEvent]struct{}
+}
+
+func newEventHub() *eventHub {
+	return &eventHub{subs: make(map[chan WPAEvent]struct{})}
+}
+
+func (h *eventHub) subscribe() chan WPAEvent {
+	ch := make(chan WPAEvent, eventBufferSize)
+	h.mu.Lock()
+	h.subs[ch] = struct{}{}
+	h.mu.Unlock()
+	return ch
+}
+
+func (h *eventHub) unsubscribe(ch <-chan WPAEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for sub := range h.subs {
+		if sub == ch {
+			delete(h.subs, sub)
+		}
+	}
+}
+
+func (h *eventHub) emit(event WPAEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.subs {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
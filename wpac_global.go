@@ -0,0 +1,105 @@
+package wpac
+
+import (
+	"errors"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	DebugLevelMsgDump = "msgdump"
+	DebugLevelDebug   = "debug"
+	DebugLevelInfo    = "info"
+	DebugLevelWarning = "warning"
+	DebugLevelError   = "error"
+)
+
+func (w *WPA) getStrings(name string) ([]string, error) {
+	prop, err := w.bus.GetProperty(name)
+	if err != nil {
+		return nil, err
+	}
+	values, ok := prop.([]string)
+	if !ok {
+		return nil, errors.New("invalid " + name)
+	}
+	return values, nil
+}
+
+func (w *WPA) getBool(name string) (bool, error) {
+	prop, err := w.bus.GetProperty(name)
+	if err != nil {
+		return false, err
+	}
+	value, ok := prop.(bool)
+	if !ok {
+		return false, errors.New("invalid " + name)
+	}
+	return value, nil
+}
+
+func (w *WPA) setProperty(name string, value interface{}) error {
+	return w.bus.BusObject().SetProperty(name, dbus.MakeVariant(value))
+}
+
+// Capabilities Global capabilities of wpa_supplicant, e.g. "ap", "ibss-rsn", "p2p", "interworking".
+func (w *WPA) Capabilities() ([]string, error) {
+	return w.getStrings("fi.w1.wpa_supplicant1.Capabilities")
+}
+
+// EapMethods EAP methods wpa_supplicant was built with.
+func (w *WPA) EapMethods() ([]string, error) {
+	return w.getStrings("fi.w1.wpa_supplicant1.EapMethods")
+}
+
+// DebugLevel One of msgdump, debug, info, warning, error.
+func (w *WPA) DebugLevel() (string, error) {
+	prop, err := w.bus.GetProperty("fi.w1.wpa_supplicant1.DebugLevel")
+	if err != nil {
+		return "", err
+	}
+	level, ok := prop.(string)
+	if !ok {
+		return "", errors.New("invalid debug level")
+	}
+	return level, nil
+}
+
+func (w *WPA) SetDebugLevel(level string) error {
+	return w.setProperty("fi.w1.wpa_supplicant1.DebugLevel", level)
+}
+
+// DebugTimestamp Whether timestamps are prepended to debug messages.
+func (w *WPA) DebugTimestamp() (bool, error) {
+	return w.getBool("fi.w1.wpa_supplicant1.DebugTimestamp")
+}
+
+func (w *WPA) SetDebugTimestamp(enabled bool) error {
+	return w.setProperty("fi.w1.wpa_supplicant1.DebugTimestamp", enabled)
+}
+
+// DebugShowKeys Whether secrets (keys, passwords) are included in debug messages.
+func (w *WPA) DebugShowKeys() (bool, error) {
+	return w.getBool("fi.w1.wpa_supplicant1.DebugShowKeys")
+}
+
+func (w *WPA) SetDebugShowKeys(enabled bool) error {
+	return w.setProperty("fi.w1.wpa_supplicant1.DebugShowKeys", enabled)
+}
+
+// WFDIEs Wi-Fi Display subelements.
+func (w *WPA) WFDIEs() ([]byte, error) {
+	prop, err := w.bus.GetProperty("fi.w1.wpa_supplicant1.WFDIEs")
+	if err != nil {
+		return nil, err
+	}
+	ies, ok := prop.([]byte)
+	if !ok {
+		return nil, errors.New("invalid WFDIEs")
+	}
+	return ies, nil
+}
+
+func (w *WPA) SetWFDIEs(ies []byte) error {
+	return w.setProperty("fi.w1.wpa_supplicant1.WFDIEs", ies)
+}
@@ -0,0 +1,132 @@
+package wpac
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/godbus/dbus/v5"
+)
+
+var (
+	ErrNetworkNotFound     = errors.New("network not found")
+	ErrUnsupportedSecurity = errors.New("unsupported network security")
+)
+
+// ScanSSIDs runs an active scan which probes for ssids, so hidden networks
+// answer as well, and waits for it to finish.
+func (self *WPAInterface) ScanSSIDs(ctx context.Context, ssids ...string) error {
+	probes := make([][]byte, 0, len(ssids))
+	for _, ssid := range ssids {
+		probes = append(probes, []byte(ssid))
+	}
+	args := make(map[string]dbus.Variant)
+	args["Type"] = dbus.MakeVariant("active")
+	args["SSIDs"] = dbus.MakeVariant(probes)
+
+	signal := self.bus.Subscribe()
+	defer self.bus.Unsubscribe(signal)
+	obj := self.bus.object(self.Path())
+	if call := obj.Call("fi.w1.wpa_supplicant1.Interface.Scan", 0, args); call.Err != nil {
+		return call.Err
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case event := <-signal:
+			if event.Name != SignalScanDone || event.Path != self.Path() {
+				continue
+			}
+			if len(event.Body) > 0 {
+				if success, ok := event.Body[0].(bool); ok && !success {
+					return errors.New("scan failed")
+				}
+			}
+			return nil
+		}
+	}
+}
+
+// GetAllBSSList unlike GetBSSList keeps BSSs with blank or unusual SSIDs,
+// e.g. hidden networks which didn't answer a probe yet.
+func (self *WPAInterface) GetAllBSSList() []WPABSS {
+	bsss := []WPABSS{}
+	obj := self.bus.object(self.Path())
+	prop, err := obj.GetProperty("fi.w1.wpa_supplicant1.Interface.BSSs")
+	if err != nil {
+		return bsss
+	}
+	for _, path := range prop.Value().([]dbus.ObjectPath) {
+		bsss = append(bsss, NewBSS(self.bus, path))
+	}
+	resolveOWETransition(bsss)
+	return bsss
+}
+
+// profileForBSS builds a profile matching the security bss advertises
+func profileForBSS(bss WPABSS) (map[string]dbus.Variant, error) {
+	switch {
+	case bss.IsOWE() || bss.IsOWETransition():
+		return WPAConfig().GetOWE(bss), nil
+	case bss.WPA2 != nil:
+		if !contains(bss.WPA2.KeyMgmt, "wpa-psk") {
+			return nil, fmt.Errorf("%w (rsn %v)", ErrUnsupportedSecurity, bss.WPA2.KeyMgmt)
+		}
+		if bss.WPA != nil && contains(bss.WPA.KeyMgmt, "wpa-psk") {
+			return WPAConfig().GetWPAWPA2PSK(bss)
+		}
+		return WPAConfig().GetWPA2PSK(bss)
+	case bss.WPA != nil:
+		if !contains(bss.WPA.KeyMgmt, "wpa-psk") {
+			return nil, fmt.Errorf("%w (wpa %v)", ErrUnsupportedSecurity, bss.WPA.KeyMgmt)
+		}
+		return WPAConfig().GetWPAPSK(bss)
+	case bss.Privacy:
+		return WPAConfig().GetWEP(WEPConfig{SSID: bss.SSID, Keys: [4]Secret{bss.PSK}})
+	}
+	return WPAConfig().GetWPANone(bss), nil
+}
+
+// ConnectHidden probes for a non-broadcast ssid, infers its security from the
+// strongest probe response and connects with credential, empty for open
+// networks and the first key for WEP. The network isn't pinned to the BSSID so it can roam.
+func (self *WPAInterface) ConnectHidden(ctx context.Context, ssid string, credential Secret) (*WPANetwork, error) {
+	if err := self.ScanSSIDs(ctx, ssid); err != nil {
+		return nil, err
+	}
+	var (
+		best  WPABSS
+		found bool
+	)
+	for _, bss := range self.GetAllBSSList() {
+		if bss.SSID == ssid && (!found || bss.Signal > best.Signal) {
+			best, found = bss, true
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("%w (%s)", ErrNetworkNotFound, ssid)
+	}
+
+	best.PSK = credential
+	profile, err := profileForBSS(best)
+	if err != nil {
+		return nil, err
+	}
+	delete(profile, WPANetworkBSSID)
+	profile["scan_ssid"] = dbus.MakeVariant(int32(1))
+	if err := self.ValidateProfile(profile); err != nil {
+		return nil, err
+	}
+
+	network, err := self.AddNetwork(profile)
+	if err != nil {
+		return nil, err
+	}
+	if err := self.SelectNetwork(network.ID); err != nil {
+		// don't leave a network behind which was never used
+		self.RemoveNetwork(network.ID)
+		return nil, err
+	}
+	return network, nil
+}
@@ -0,0 +1,289 @@
+package wpac
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const SignalANQPQueryDone = "fi.w1.wpa_supplicant1.Interface.ANQPQueryDone"
+
+// ANQP info IDs (IEEE 802.11u)
+const (
+	ANQPCapabilityList      uint16 = 257
+	ANQPVenueName           uint16 = 258
+	ANQPNetworkAuthType     uint16 = 260
+	ANQPRoamingConsortium   uint16 = 261
+	ANQPIPAddrTypeAvailInfo uint16 = 262
+	ANQPNAIRealm            uint16 = 263
+	ANQP3GPPCellular        uint16 = 264
+	ANQPDomainName          uint16 = 268
+)
+
+// Credential A Hotspot 2.0 credential, wpa_supplicant picks networks which
+// accept it through Interworking. RoamingConsortiums are hex OIs, e.g. "5a03ba0000".
+type Credential struct {
+	Realm                 string
+	Username              string
+	Password              Secret
+	PasswordRef           string
+	CACert                string
+	ClientCert            string
+	PrivateKey            string
+	PrivateKeyPassword    Secret
+	PrivateKeyPasswordRef string
+	Domain                string
+	EAP                   string
+	Phase2                string
+	RoamingConsortiums    []string
+	Priority              int
+}
+
+func (cred Credential) args() map[string]dbus.Variant {
+	args := make(map[string]dbus.Variant)
+	params := map[string]string{
+		"realm":              cred.Realm,
+		"username":           cred.Username,
+		"password":           cred.Password.Reveal(),
+		"ca_cert":            cred.CACert,
+		"client_cert":        cred.ClientCert,
+		"private_key":        cred.PrivateKey,
+		"private_key_passwd": cred.PrivateKeyPassword.Reveal(),
+		"domain":             cred.Domain,
+		"eap":                cred.EAP,
+		"phase2":             cred.Phase2,
+	}
+	for k, v := range params {
+		if v != "" {
+			args[k] = dbus.MakeVariant(v)
+		}
+	}
+	refs := map[string]string{
+		"password":           cred.PasswordRef,
+		"private_key_passwd": cred.PrivateKeyPasswordRef,
+	}
+	for k, ref := range refs {
+		if ref != "" {
+			args[k] = dbus.MakeVariant(SecretRef(ref))
+		}
+	}
+	switch len(cred.RoamingConsortiums) {
+	case 0:
+	case 1:
+		args["roaming_consortium"] = dbus.MakeVariant(cred.RoamingConsortiums[0])
+	default:
+		args["roaming_consortiums"] = dbus.MakeVariant(strings.Join(cred.RoamingConsortiums, ","))
+	}
+	if cred.Priority != 0 {
+		args["priority"] = dbus.MakeVariant(int32(cred.Priority))
+	}
+	return args
+}
+
+// AddCred adds a Hotspot 2.0 credential, wpa_supplicant needs interworking=1 and hs20=1
+func (self *WPAInterface) AddCred(cred Credential) (dbus.ObjectPath, error) {
+	if cred.Realm == "" && len(cred.RoamingConsortiums) == 0 && cred.Domain == "" {
+		return "", errors.New("credential needs a realm, domain or roaming consortium")
+	}
+	args, err := resolveSecrets(self.bus.secretStore(), cred.args(), "")
+	if err != nil {
+		return "", err
+	}
+	var path dbus.ObjectPath
+	obj := self.bus.object(self.Path())
+	if err := obj.Call("fi.w1.wpa_supplicant1.Interface.AddCred", 0, args).Store(&path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+func (self *WPAInterface) RemoveCred(path dbus.ObjectPath) error {
+	obj := self.bus.object(self.Path())
+	if call := obj.Call("fi.w1.wpa_supplicant1.Interface.RemoveCred", 0, path); call.Err != nil {
+		return call.Err
+	}
+	return nil
+}
+
+func (self *WPAInterface) RemoveAllCreds() error {
+	obj := self.bus.object(self.Path())
+	if call := obj.Call("fi.w1.wpa_supplicant1.Interface.RemoveAllCreds", 0); call.Err != nil {
+		return call.Err
+	}
+	return nil
+}
+
+// InterworkingSelect matches the credentials against the ANQP results of the
+// last scan and connects to the best network
+func (self *WPAInterface) InterworkingSelect() error {
+	obj := self.bus.object(self.Path())
+	if call := obj.Call("fi.w1.wpa_supplicant1.Interface.InterworkingSelect", 0); call.Err != nil {
+		return call.Err
+	}
+	return nil
+}
+
+// ANQPGet queries the ANQP elements ids, e.g. ANQPVenueName, of bssid and
+// waits for the answer, which is decoded by WPABSS.ANQP.
+func (self *WPAInterface) ANQPGet(ctx context.Context, bssid string, ids ...uint16) error {
+	args := make(map[string]dbus.Variant)
+	args["addr"] = dbus.MakeVariant(bssid)
+	args["ids"] = dbus.MakeVariant(ids)
+
+	signal := self.bus.Subscribe()
+	defer self.bus.Unsubscribe(signal)
+	obj := self.bus.object(self.Path())
+	if call := obj.Call("fi.w1.wpa_supplicant1.Interface.ANQPGet", 0, args); call.Err != nil {
+		return call.Err
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case event := <-signal:
+			if event.Name != SignalANQPQueryDone || event.Path != self.Path() || len(event.Body) < 2 {
+				continue
+			}
+			if addr, _ := event.Body[0].(string); !strings.EqualFold(addr, bssid) {
+				continue
+			}
+			if result, _ := event.Body[1].(string); result != "SUCCESS" {
+				return fmt.Errorf("anqp query %s failed (%s)", bssid, result)
+			}
+			return nil
+		}
+	}
+}
+
+type VenueName struct {
+	Lang string `json:"lang"`
+	Name string `json:"name"`
+}
+
+// NAIRealm Realms sharing the EAP methods, e.g. EAP type 21 (TTLS)
+type NAIRealm struct {
+	Realms     []string `json:"realms"`
+	EAPMethods []uint8  `json:"eap_methods"`
+}
+
+// ANQPInfo Decoded ANQP elements of a BSS, raw elements are kept by name
+type ANQPInfo struct {
+	VenueGroup         uint8             `json:"venue_group"`
+	VenueType          uint8             `json:"venue_type"`
+	VenueNames         []VenueName       `json:"venue_names,omitempty"`
+	NAIRealms          []NAIRealm        `json:"nai_realms,omitempty"`
+	DomainNames        []string          `json:"domain_names,omitempty"`
+	RoamingConsortiums []string          `json:"roaming_consortiums,omitempty"`
+	Raw                map[string][]byte `json:"-"`
+}
+
+// ANQP decodes the fi.w1.wpa_supplicant1.BSS.ANQP property, see ANQPGet
+func (wb *WPABSS) ANQP() (ANQPInfo, error) {
+	info := ANQPInfo{Raw: make(map[string][]byte)}
+	prop, err := wb.busObject.GetProperty("fi.w1.wpa_supplicant1.BSS.ANQP")
+	if err != nil {
+		return info, err
+	}
+	dict, ok := prop.Value().(map[string]dbus.Variant)
+	if !ok {
+		return info, nil
+	}
+	for k, v := range dict {
+		payload, ok := v.Value().([]byte)
+		if !ok {
+			continue
+		}
+		info.Raw[k] = payload
+		switch k {
+		case "VenueName":
+			info.VenueGroup, info.VenueType, info.VenueNames = decodeVenueName(payload)
+		case "NAIRealm":
+			info.NAIRealms = decodeNAIRealm(payload)
+		case "DomainName":
+			info.DomainNames = decodeLengthPrefixed(payload)
+		case "RoamingConsortium":
+			for _, oi := range decodeLengthPrefixed(payload) {
+				info.RoamingConsortiums = append(info.RoamingConsortiums, hex.EncodeToString([]byte(oi)))
+			}
+		}
+	}
+	return info, nil
+}
+
+// decodeVenueName venue group, venue type, then (length, language code, name) tuples
+func decodeVenueName(b []byte) (uint8, uint8, []VenueName) {
+	if len(b) < 2 {
+		return 0, 0, nil
+	}
+	group, kind := b[0], b[1]
+	names := []VenueName{}
+	for _, duple := range decodeLengthPrefixed(b[2:]) {
+		if len(duple) < 3 {
+			continue
+		}
+		names = append(names, VenueName{
+			Lang: strings.TrimRight(duple[:3], "\x00"),
+			Name: duple[3:],
+		})
+	}
+	return group, kind, names
+}
+
+// decodeLengthPrefixed splits (length, value) tuples with a one byte length
+func decodeLengthPrefixed(b []byte) []string {
+	values := []string{}
+	for len(b) > 0 {
+		n := int(b[0])
+		if len(b) < 1+n {
+			break
+		}
+		values = append(values, string(b[1:1+n]))
+		b = b[1+n:]
+	}
+	return values
+}
+
+// decodeNAIRealm count, then per realm: data length, encoding, realm length,
+// realms separated by ';', EAP method count and (length, method, params) tuples
+func decodeNAIRealm(b []byte) []NAIRealm {
+	if len(b) < 2 {
+		return nil
+	}
+	count := int(binary.LittleEndian.Uint16(b))
+	b = b[2:]
+	realms := []NAIRealm{}
+	for i := 0; i < count && len(b) >= 2; i++ {
+		n := int(binary.LittleEndian.Uint16(b))
+		if len(b) < 2+n || n < 2 {
+			break
+		}
+		data := b[2 : 2+n]
+		b = b[2+n:]
+
+		realmLen := int(data[1])
+		if len(data) < 2+realmLen {
+			continue
+		}
+		realm := NAIRealm{Realms: strings.Split(string(data[2:2+realmLen]), ";")}
+		data = data[2+realmLen:]
+		if len(data) > 0 {
+			methods := int(data[0])
+			data = data[1:]
+			for j := 0; j < methods && len(data) >= 2; j++ {
+				m := int(data[0])
+				if m < 1 || len(data) < 1+m {
+					break
+				}
+				realm.EAPMethods = append(realm.EAPMethods, data[1])
+				data = data[1+m:]
+			}
+		}
+		realms = append(realms, realm)
+	}
+	return realms
+}
@@ -0,0 +1,92 @@
+package wpac
+
+import (
+	"reflect"
+	"testing"
+)
+
+// ANQP payloads as wpa_supplicant reports them in BSS.ANQP
+const (
+	// venue group 2 type 8, "Example Cafe" (eng) and "Café Exemple" (fre)
+	anqpVenueName = "0208" + "0f656e674578616d706c652043616665" + "10667265436166c3a9204578656d706c65"
+	// example.com with EAP-TTLS (MSCHAPv2, username/password) and a.org;b.org with EAP-TLS
+	anqpNAIRealm = "0200" +
+		"1700" + "000b6578616d706c652e636f6d" + "01" + "081502020104050107" +
+		"1100" + "000b612e6f72673b622e6f7267" + "01" + "020d00"
+	anqpDomainName = "0b6578616d706c652e636f6d" + "07666f6f2e6f7267"
+)
+
+func TestDecodeVenueName(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+		group   uint8
+		kind    uint8
+		names   []VenueName
+	}{
+		{"two languages", anqpVenueName, 2, 8, []VenueName{{"eng", "Example Cafe"}, {"fre", "Café Exemple"}}},
+		{"truncated name", anqpVenueName[:len(anqpVenueName)-4], 2, 8, []VenueName{{"eng", "Example Cafe"}}},
+		{"no names", "0208", 2, 8, []VenueName{}},
+		{"language only", "0208" + "02656e", 2, 8, []VenueName{}},
+		{"too short", "02", 0, 0, nil},
+	}
+	for _, tt := range tests {
+		group, kind, names := decodeVenueName(mustHex(t, tt.payload))
+		if group != tt.group || kind != tt.kind || !reflect.DeepEqual(names, tt.names) {
+			t.Errorf("%s: decoded %d %d %v, want %d %d %v", tt.name, group, kind, names, tt.group, tt.kind, tt.names)
+		}
+	}
+}
+
+func TestDecodeNAIRealm(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+		realms  []NAIRealm
+	}{
+		{"two realms", anqpNAIRealm, []NAIRealm{
+			{Realms: []string{"example.com"}, EAPMethods: []uint8{21}},
+			{Realms: []string{"a.org", "b.org"}, EAPMethods: []uint8{13}},
+		}},
+		{"truncated second realm", anqpNAIRealm[:len(anqpNAIRealm)-10], []NAIRealm{
+			{Realms: []string{"example.com"}, EAPMethods: []uint8{21}},
+		}},
+		{"count above realms", "0500" + anqpNAIRealm[4:], []NAIRealm{
+			{Realms: []string{"example.com"}, EAPMethods: []uint8{21}},
+			{Realms: []string{"a.org", "b.org"}, EAPMethods: []uint8{13}},
+		}},
+		{"without eap methods", "0100" + "0d00" + "000b6578616d706c652e636f6d", []NAIRealm{
+			{Realms: []string{"example.com"}},
+		}},
+		{"truncated eap method", "0100" + "0f00" + "000b6578616d706c652e636f6d" + "0108", []NAIRealm{
+			{Realms: []string{"example.com"}},
+		}},
+		{"realm beyond data", "0100" + "0400" + "000b6578", []NAIRealm{}},
+		{"no realms", "0000", []NAIRealm{}},
+		{"too short", "01", nil},
+	}
+	for _, tt := range tests {
+		if realms := decodeNAIRealm(mustHex(t, tt.payload)); !reflect.DeepEqual(realms, tt.realms) {
+			t.Errorf("%s: decoded %+v, want %+v", tt.name, realms, tt.realms)
+		}
+	}
+}
+
+func TestDecodeLengthPrefixed(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+		values  []string
+	}{
+		{"domain names", anqpDomainName, []string{"example.com", "foo.org"}},
+		{"truncated", anqpDomainName[:len(anqpDomainName)-2], []string{"example.com"}},
+		{"roaming consortiums", "03001bc5" + "055a03ba0000", []string{"\x00\x1b\xc5", "\x5a\x03\xba\x00\x00"}},
+		{"empty value", "00", []string{""}},
+		{"empty", "", []string{}},
+	}
+	for _, tt := range tests {
+		if values := decodeLengthPrefixed(mustHex(t, tt.payload)); !reflect.DeepEqual(values, tt.values) {
+			t.Errorf("%s: decoded %q, want %q", tt.name, values, tt.values)
+		}
+	}
+}
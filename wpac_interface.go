@@ -4,8 +4,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
 	"regexp"
+	"sync"
 	"time"
 
 	"github.com/godbus/dbus/v5"
@@ -18,42 +18,142 @@ const (
 	ErrInterfaceExists = "wpa_supplicant already controls this interface."
 )
 
+// InterfaceInfo Identity of an interface controlled by wpa_supplicant
+type InterfaceInfo struct {
+	Path         dbus.ObjectPath `json:"path"`
+	Ifname       string          `json:"ifname"`
+	Driver       string          `json:"driver"`
+	BridgeIfname string          `json:"bridge_ifname"`
+}
+
 type WPAInterface struct {
-	bus       *WPADBus
-	ctx       context.Context
-	ifacePath dbus.ObjectPath
-	networks  map[int]WPANetwork
+	bus        *WPADBus
+	ctx        context.Context
+	cancel     context.CancelFunc
+	ifname     string
+	ifacePath  dbus.ObjectPath
+	pathMu     sync.RWMutex
+	networks   map[int]WPANetwork
+	currentBSS dbus.ObjectPath
+	// currentBSSID is cached, the BSS object may be gone once CurrentBSS changes
+	currentBSSID string
+	// observed the path whose signals are matched, removed by Detach
+	observed dbus.ObjectPath
+	state    string
+	// reason the last DisconnectReason of the current attempt
+	reason    int32
+	mu        sync.Mutex
+	eapol     EAPOLStatus
+	opts      InterfaceOptions
+	listening bool
+	// saved networks added through wpac, re-applied after wpa_supplicant restarts
+	saved map[dbus.ObjectPath]map[string]dbus.Variant
 }
 
 //func NewWPAInterface(bus *WPADBus, objectPath dbus.ObjectPath) *WPAInterface {
 func NewWPAInterface(ctx context.Context, bus *WPADBus) *WPAInterface {
+	ctx, cancel := context.WithCancel(ctx)
 	wi := &WPAInterface{
 		bus:      bus,
 		ctx:      ctx,
+		cancel:   cancel,
 		networks: make(map[int]WPANetwork),
+		saved:    make(map[dbus.ObjectPath]map[string]dbus.Variant),
 	}
 	return wi
 }
 
+func (w *WPAInterface) Ifname() string {
+	return w.ifname
+}
+
+// Path of the interface object, it changes when recreate follows a restarted wpa_supplicant
+func (w *WPAInterface) Path() dbus.ObjectPath {
+	w.pathMu.RLock()
+	defer w.pathMu.RUnlock()
+	return w.ifacePath
+}
+
+func (w *WPAInterface) setPath(path dbus.ObjectPath) {
+	w.pathMu.Lock()
+	w.ifacePath = path
+	w.pathMu.Unlock()
+}
+
+// Detach stops the interface event listeners, the interface is left untouched in wpa_supplicant
+func (w *WPAInterface) Detach() {
+	w.cancel()
+	w.mu.Lock()
+	path := w.observed
+	w.observed = ""
+	w.mu.Unlock()
+	if path != "" {
+		w.bus.Signal.RemoveObserver("fi.w1.wpa_supplicant1.Interface", path)
+	}
+}
+
+const (
+	DriverNL80211 = "nl80211"
+	DriverWext    = "wext"
+	DriverWired   = "wired"
+
+	InterfaceTypeSTA = "sta"
+	InterfaceTypeAP  = "ap"
+)
+
+// InterfaceOptions Arguments of fi.w1.wpa_supplicant1.CreateInterface.
+// Driver may be a fallback chain such as "nl80211,wext", empty fields are
+// not passed to wpa_supplicant except Driver which defaults to nl80211.
+type InterfaceOptions struct {
+	Ifname       string
+	Driver       string
+	ConfigFile   string
+	BridgeIfname string
+	Type         string
+}
+
 // CreateInterface ...
 func (w *WPAInterface) CreateInterface(ifname string) error {
-	if ifname == "" {
-		ifname = DefaultIfaceName
+	return w.CreateInterfaceWithOptions(InterfaceOptions{Ifname: ifname})
+}
+
+func (w *WPAInterface) CreateInterfaceWithOptions(opts InterfaceOptions) error {
+	if opts.Ifname == "" {
+		opts.Ifname = DefaultIfaceName
+	}
+	if opts.Driver == "" {
+		opts.Driver = DriverNL80211
 	}
-	if ifpath, err := w.GetInterface(ifname); err == nil {
-		w.ifacePath = ifpath
+	w.ifname = opts.Ifname
+	w.opts = opts
+	return w.createInterface(opts)
+}
+
+// createInterface leaves ifname and opts alone, listeners read them while recreate runs
+func (w *WPAInterface) createInterface(opts InterfaceOptions) error {
+	if ifpath, err := w.GetInterface(opts.Ifname); err == nil {
+		w.setPath(ifpath)
 		return nil
 	}
 
 	args := make(map[string]dbus.Variant)
-	args["Ifname"] = dbus.MakeVariant(ifname)
-	args["Driver"] = dbus.MakeVariant("nl80211")
+	args["Ifname"] = dbus.MakeVariant(opts.Ifname)
+	args["Driver"] = dbus.MakeVariant(opts.Driver)
+	if opts.ConfigFile != "" {
+		args["ConfigFile"] = dbus.MakeVariant(opts.ConfigFile)
+	}
+	if opts.BridgeIfname != "" {
+		args["BridgeIfname"] = dbus.MakeVariant(opts.BridgeIfname)
+	}
+	if opts.Type != "" {
+		args["Type"] = dbus.MakeVariant(opts.Type)
+	}
 	iface, err := w.bus.CallWithVariant("fi.w1.wpa_supplicant1.CreateInterface", args)
 	if err != nil && err.Error() != ErrInterfaceExists {
-		w.ifacePath = ""
+		w.setPath("")
 		return err
 	}
-	w.ifacePath = iface
+	w.setPath(iface)
 	return nil
 }
 
@@ -74,17 +174,32 @@ func (w *WPAInterface) GetInterfaces() ([]dbus.ObjectPath, error) {
 	if !ok {
 		return nil, errors.New("get interfaces error")
 	}
-	for _, iface := range ifaces {
-		log.Printf("iface: %s\n", iface)
+	return ifaces, nil
+}
+
+// GetInterfaceInfo reads Ifname, Driver and BridgeIfname of an interface object
+func GetInterfaceInfo(bus *WPADBus, path dbus.ObjectPath) (InterfaceInfo, error) {
+	info := InterfaceInfo{Path: path}
+	obj := bus.object(path)
+	prop, err := obj.GetProperty("fi.w1.wpa_supplicant1.Interface.Ifname")
+	if err != nil {
+		return info, err
 	}
-	return nil, nil
+	info.Ifname, _ = prop.Value().(string)
+	if prop, err := obj.GetProperty("fi.w1.wpa_supplicant1.Interface.Driver"); err == nil {
+		info.Driver, _ = prop.Value().(string)
+	}
+	if prop, err := obj.GetProperty("fi.w1.wpa_supplicant1.Interface.BridgeIfname"); err == nil {
+		info.BridgeIfname, _ = prop.Value().(string)
+	}
+	return info, nil
 }
 
 func (w *WPAInterface) CloseInterface() error {
-	if w.ifacePath == "" {
+	if w.Path() == "" {
 		return errors.New("interface doesn't exist or doesn't represent an interface")
 	}
-	ifacePath := dbus.ObjectPath(w.ifacePath)
+	ifacePath := dbus.ObjectPath(w.Path())
 	if _, err := w.bus.CallWithPath("fi.w1.wpa_supplicant1.RemoveInterface", ifacePath); err != nil {
 		return err
 	}
@@ -92,7 +207,7 @@ func (w *WPAInterface) CloseInterface() error {
 }
 
 func (self *WPAInterface) State() string {
-	obj := self.bus.Connection.Object("fi.w1.wpa_supplicant1", self.ifacePath)
+	obj := self.bus.object(self.Path())
 	state, err := obj.GetProperty("fi.w1.wpa_supplicant1.Interface.State")
 	if err != nil {
 		return "unknown"
@@ -102,7 +217,7 @@ func (self *WPAInterface) State() string {
 
 // GetScanInterval Time (in seconds) between scans for a suitable AP. Must be >= 0.
 func (self *WPAInterface) GetScanInterval() (int32, error) {
-	obj := self.bus.Connection.Object("fi.w1.wpa_supplicant1", self.ifacePath)
+	obj := self.bus.object(self.Path())
 	interval, err := obj.GetProperty("fi.w1.wpa_supplicant1.Interface.ScanInterval")
 	if err != nil {
 		return -1, err
@@ -112,7 +227,7 @@ func (self *WPAInterface) GetScanInterval() (int32, error) {
 
 func (self *WPAInterface) SetScanInterval(interval int32) error {
 	value := dbus.MakeVariant(interval)
-	obj := self.bus.Connection.Object("fi.w1.wpa_supplicant1", self.ifacePath)
+	obj := self.bus.object(self.Path())
 	err := obj.SetProperty("fi.w1.wpa_supplicant1.Interface.ScanInterval", value)
 	if err != nil {
 		return err
@@ -123,7 +238,7 @@ func (self *WPAInterface) SetScanInterval(interval int32) error {
 func (self *WPAInterface) Scan() error {
 	args := make(map[string]dbus.Variant)
 	args["Type"] = dbus.MakeVariant("passive")
-	obj := self.bus.Connection.Object("fi.w1.wpa_supplicant1", self.ifacePath)
+	obj := self.bus.object(self.Path())
 	if call := obj.Call("fi.w1.wpa_supplicant1.Interface.Scan", 0, args); call.Err != nil {
 		return call.Err
 	}
@@ -133,17 +248,13 @@ func (self *WPAInterface) Scan() error {
 func (self *WPAInterface) GetBSSList() []WPABSS {
 	newBSSs := []WPABSS{}
 	tmpBSSs := make(map[string]string)
-	obj := self.bus.Connection.Object("fi.w1.wpa_supplicant1", self.ifacePath)
-	bsss, err := obj.GetProperty("fi.w1.wpa_supplicant1.Interface.BSSs")
-	if err == nil {
-		re := regexp.MustCompile(`^[\w\s_.-]*$`)
-		for _, bssObjectPath := range bsss.Value().([]dbus.ObjectPath) {
-			bss := NewBSS(self.bus, bssObjectPath)
-			if re.MatchString(bss.SSID) && bss.SSID != "" {
-				if _, found := tmpBSSs[bss.BSSID]; !found {
-					tmpBSSs[bss.BSSID] = bss.BSSID
-					newBSSs = append(newBSSs, bss)
-				}
+	re := regexp.MustCompile(`^[\w\s_.-]*$`)
+	// GetAllBSSList names the hidden BSS of an OWE transition pair first
+	for _, bss := range self.GetAllBSSList() {
+		if re.MatchString(bss.SSID) && bss.SSID != "" {
+			if _, found := tmpBSSs[bss.BSSID]; !found {
+				tmpBSSs[bss.BSSID] = bss.BSSID
+				newBSSs = append(newBSSs, bss)
 			}
 		}
 	}
@@ -157,7 +268,9 @@ func (self *WPAInterface) AutoScan() ([]WPABSS, error) {
 	}
 
 	done := make(chan struct{})
-	signal := self.bus.GetSignal()
+	signal := self.bus.Subscribe()
+	defer self.bus.Unsubscribe(signal)
+	path := self.Path()
 	timeout := time.After(time.Duration(interval) * time.Second)
 	if err := self.Scan(); err != nil {
 		return nil, err
@@ -170,9 +283,11 @@ func (self *WPAInterface) AutoScan() ([]WPABSS, error) {
 				return
 			case <-timeout:
 				close(done)
+				return
 			case event := <-signal:
-				if event.Name == SignalScanDone {
+				if event.Name == SignalScanDone && event.Path == path {
 					close(done)
+					return
 				}
 			}
 		}
@@ -192,64 +307,105 @@ func (self *WPAInterface) AddNetwork(args map[string]dbus.Variant) (*WPANetwork,
 	// 	}
 	// }
 
-	obj := self.bus.Connection.Object("fi.w1.wpa_supplicant1", self.ifacePath)
-	call := obj.Call("fi.w1.wpa_supplicant1.Interface.AddNetwork", 0, args)
+	// saved keeps the references, only wpa_supplicant gets the secrets
+	resolved, err := resolveSecrets(self.bus.secretStore(), args, "")
+	if err != nil {
+		return nil, err
+	}
+	obj := self.bus.object(self.Path())
+	call := obj.Call("fi.w1.wpa_supplicant1.Interface.AddNetwork", 0, resolved)
 	if call.Err != nil || len(call.Body) == 0 {
 		return nil, call.Err
 	}
 
 	networkObj := dbus.ObjectPath(call.Body[0].(dbus.ObjectPath))
-	id := len(self.networks)
+	self.mu.Lock()
+	self.saved[networkObj] = args
+	self.mu.Unlock()
 	network := NewWPANetwork(self.bus, networkObj)
-	self.networks[id] = network
+	self.mu.Lock()
+	self.networks[network.ID] = network
+	self.mu.Unlock()
 	return &network, nil
 }
 
+// network looks up a known network by id, recreate may swap the map from the watcher
+func (self *WPAInterface) network(id int) (WPANetwork, bool) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	network, found := self.networks[id]
+	return network, found
+}
+
 func (self *WPAInterface) SetNetwork(id int, args map[string]dbus.Variant) error {
-	if network, found := self.networks[id]; found {
-		return network.writeProp(args)
+	if network, found := self.network(id); found {
+		resolved, err := resolveSecrets(self.bus.secretStore(), args, network.SSID)
+		if err != nil {
+			return err
+		}
+		if err := network.writeProp(resolved); err != nil {
+			return err
+		}
+		self.mu.Lock()
+		if saved, found := self.saved[network.Object]; found {
+			for k, v := range args {
+				saved[k] = v
+			}
+		}
+		self.mu.Unlock()
+		return nil
 	}
 	return fmt.Errorf("network %d not found", id)
 }
 
 func (self *WPAInterface) SetNetworkEnabled(id int, enabled bool) error {
-	if network, found := self.networks[id]; found {
+	if network, found := self.network(id); found {
 		return network.writeEnable(enabled)
 	}
 	return fmt.Errorf("network %d not found", id)
 }
 
 func (self *WPAInterface) SelectNetwork(id int) error {
-	if network, found := self.networks[id]; found {
-		obj := self.bus.Connection.Object("fi.w1.wpa_supplicant1", self.ifacePath)
+	if network, found := self.network(id); found {
+		obj := self.bus.object(self.Path())
 		call := obj.Call("fi.w1.wpa_supplicant1.Interface.SelectNetwork", 0, network.Object)
 		if call.Err != nil {
 			return call.Err
 		}
+		return nil
 	}
-	return nil
+	return fmt.Errorf("network %d not found", id)
 }
 
 func (self *WPAInterface) RemoveNetwork(id int) error {
-	if networkObj, found := self.networks[id]; found {
-		obj := self.bus.Connection.Object("fi.w1.wpa_supplicant1", self.ifacePath)
+	if networkObj, found := self.network(id); found {
+		obj := self.bus.object(self.Path())
 		if call := obj.Call("fi.w1.wpa_supplicant1.Interface.RemoveNetwork", 0, networkObj.Object); call.Err != nil {
 			return call.Err
 		}
+		self.mu.Lock()
+		delete(self.saved, networkObj.Object)
+		delete(self.networks, id)
+		self.mu.Unlock()
+		return nil
 	}
 	return fmt.Errorf("network (%d) not found", id)
 }
 
 func (self *WPAInterface) RemoveAllNetwork() error {
-	obj := self.bus.Connection.Object("fi.w1.wpa_supplicant1", self.ifacePath)
+	obj := self.bus.object(self.Path())
 	if call := obj.Call("fi.w1.wpa_supplicant1.Interface.RemoveAllNetworks", 0); call.Err != nil {
 		return call.Err
 	}
+	self.mu.Lock()
+	self.saved = make(map[dbus.ObjectPath]map[string]dbus.Variant)
+	self.networks = make(map[int]WPANetwork)
+	self.mu.Unlock()
 	return nil
 }
 
 func (self *WPAInterface) Disconnect() error {
-	obj := self.bus.Connection.Object("fi.w1.wpa_supplicant1", self.ifacePath)
+	obj := self.bus.object(self.Path())
 	if call := obj.Call("fi.w1.wpa_supplicant1.Interface.Disconnect", 0); call.Err != nil {
 		return call.Err
 	}
@@ -257,7 +413,7 @@ func (self *WPAInterface) Disconnect() error {
 }
 
 func (self *WPAInterface) DisconnectReason() (int32, error) {
-	obj := self.bus.Connection.Object("fi.w1.wpa_supplicant1", self.ifacePath)
+	obj := self.bus.object(self.Path())
 	state, err := obj.GetProperty("fi.w1.wpa_supplicant1.Interface.DisconnectReason")
 	if err != nil {
 		return -1, err
@@ -265,39 +421,67 @@ func (self *WPAInterface) DisconnectReason() (int32, error) {
 	return state.Value().(int32), nil
 }
 
+// AuthStatusCode The most recent IEEE 802.11 status code for authentication, see DecodeStatus.
+func (self *WPAInterface) AuthStatusCode() (int32, error) {
+	obj := self.bus.object(self.Path())
+	status, err := obj.GetProperty("fi.w1.wpa_supplicant1.Interface.AuthStatusCode")
+	if err != nil {
+		return -1, err
+	}
+	return status.Value().(int32), nil
+}
+
+// AssocStatusCode The most recent IEEE 802.11 status code for association rejection, see DecodeStatus.
+func (self *WPAInterface) AssocStatusCode() (int32, error) {
+	obj := self.bus.object(self.Path())
+	status, err := obj.GetProperty("fi.w1.wpa_supplicant1.Interface.AssocStatusCode")
+	if err != nil {
+		return -1, err
+	}
+	return status.Value().(int32), nil
+}
+
 func (self *WPAInterface) GetNetworks() (map[int]WPANetwork, error) {
-	obj := self.bus.Connection.Object("fi.w1.wpa_supplicant1", self.ifacePath)
+	obj := self.bus.object(self.Path())
 	ifaces, err := obj.GetProperty("fi.w1.wpa_supplicant1.Interface.Networks")
 	if err != nil {
 		return nil, err
 	}
 
-	self.networks = make(map[int]WPANetwork)
-
-	networks := ifaces.Value().([]dbus.ObjectPath)
-	for id, network := range networks {
+	// keyed by the wpa_supplicant network id, the last element of the object path
+	known := make(map[int]WPANetwork)
+	for _, network := range ifaces.Value().([]dbus.ObjectPath) {
 		wn := NewWPANetwork(self.bus, network)
-		self.networks[id] = wn
+		known[wn.ID] = wn
+	}
+
+	// callers get their own copy, the internal map keeps changing under mu
+	networks := make(map[int]WPANetwork, len(known))
+	for id, wn := range known {
+		networks[id] = wn
 	}
-	return self.networks, nil
+	self.mu.Lock()
+	self.networks = known
+	self.mu.Unlock()
+	return networks, nil
 }
 
 func (self *WPAInterface) GetCurrentBSS() WPABSS {
-	obj := self.bus.Connection.Object("fi.w1.wpa_supplicant1", self.ifacePath)
+	obj := self.bus.object(self.Path())
 	iface, _ := obj.GetProperty("fi.w1.wpa_supplicant1.Interface.CurrentBSS")
 	bss := iface.Value().(dbus.ObjectPath)
 	return NewBSS(self.bus, bss)
 }
 
 func (self *WPAInterface) GetCurrentNetwork() WPANetwork {
-	obj := self.bus.Connection.Object("fi.w1.wpa_supplicant1", self.ifacePath)
+	obj := self.bus.object(self.Path())
 	iface, _ := obj.GetProperty("fi.w1.wpa_supplicant1.Interface.CurrentNetwork")
 	network := iface.Value().(dbus.ObjectPath)
 	return NewWPANetwork(self.bus, network)
 }
 
 func (self *WPAInterface) Reassociate() error {
-	obj := self.bus.Connection.Object("fi.w1.wpa_supplicant1", self.ifacePath)
+	obj := self.bus.object(self.Path())
 	call := obj.Call("fi.w1.wpa_supplicant1.Interface.Reassociate", 0)
 	if call.Err != nil {
 		return call.Err
@@ -305,8 +489,48 @@ func (self *WPAInterface) Reassociate() error {
 	return nil
 }
 
+// Roam Initiate a roam to another BSS within the current ESS.
+func (self *WPAInterface) Roam(bssid string) error {
+	obj := self.bus.object(self.Path())
+	call := obj.Call("fi.w1.wpa_supplicant1.Interface.Roam", 0, bssid)
+	if call.Err != nil {
+		return call.Err
+	}
+	return nil
+}
+
+// RoamTime The most recent roam time in milliseconds.
+func (self *WPAInterface) RoamTime() (uint32, error) {
+	obj := self.bus.object(self.Path())
+	prop, err := obj.GetProperty("fi.w1.wpa_supplicant1.Interface.RoamTime")
+	if err != nil {
+		return 0, err
+	}
+	return prop.Value().(uint32), nil
+}
+
+// RoamComplete The most recent roam success or failure.
+func (self *WPAInterface) RoamComplete() (bool, error) {
+	obj := self.bus.object(self.Path())
+	prop, err := obj.GetProperty("fi.w1.wpa_supplicant1.Interface.RoamComplete")
+	if err != nil {
+		return false, err
+	}
+	return prop.Value().(bool), nil
+}
+
+// SessionLength The most recent BSS session length in milliseconds.
+func (self *WPAInterface) SessionLength() (uint32, error) {
+	obj := self.bus.object(self.Path())
+	prop, err := obj.GetProperty("fi.w1.wpa_supplicant1.Interface.SessionLength")
+	if err != nil {
+		return 0, err
+	}
+	return prop.Value().(uint32), nil
+}
+
 func (self *WPAInterface) Reattach() error {
-	obj := self.bus.Connection.Object("fi.w1.wpa_supplicant1", self.ifacePath)
+	obj := self.bus.object(self.Path())
 	call := obj.Call("fi.w1.wpa_supplicant1.Interface.Reattach", 0)
 	if call.Err != nil {
 		return call.Err
@@ -315,7 +539,7 @@ func (self *WPAInterface) Reattach() error {
 }
 
 func (self *WPAInterface) Reconnect() error {
-	obj := self.bus.Connection.Object("fi.w1.wpa_supplicant1", self.ifacePath)
+	obj := self.bus.object(self.Path())
 	call := obj.Call("fi.w1.wpa_supplicant1.Interface.Reconnect", 0)
 	if call.Err != nil {
 		return call.Err
@@ -323,20 +547,130 @@ func (self *WPAInterface) Reconnect() error {
 	return nil
 }
 
+func (w *WPAInterface) updateCurrentBSS(bss dbus.ObjectPath) {
+	bssid := w.bssidOf(bss)
+	w.mu.Lock()
+	prev, prevBSSID := w.currentBSS, w.currentBSSID
+	w.currentBSS, w.currentBSSID = bss, bssid
+	w.mu.Unlock()
+	// a roam is a BSS change which doesn't pass through "/" (disconnected)
+	if prev == "" || prev == "/" || bss == "/" || prev == bss {
+		return
+	}
+	event := Roamed{
+		Ifname: w.ifname,
+		From:   prevBSSID,
+		To:     bssid,
+	}
+	if ms, err := w.RoamTime(); err == nil {
+		event.Duration = time.Duration(ms) * time.Millisecond
+	}
+	w.bus.emit(event)
+}
+
+// bssidOf reads only the BSSID of a BSS object, "" for "/"
+func (w *WPAInterface) bssidOf(path dbus.ObjectPath) string {
+	if path == "" || path == "/" {
+		return ""
+	}
+	bss := WPABSS{busObject: w.bus.object(path)}
+	bss.readBSSID()
+	return bss.BSSID
+}
+
+func (w *WPAInterface) updateState(state string) {
+	w.mu.Lock()
+	prev := w.state
+	w.state = state
+	reason := w.reason
+	switch state {
+	case "authenticating", "associating", "completed":
+		// a new attempt or a working link, earlier reasons don't explain what follows
+		w.reason = 0
+	}
+	w.mu.Unlock()
+	if prev == state {
+		return
+	}
+	w.bus.emit(StateChanged{Ifname: w.ifname, State: state, Previous: prev})
+	switch state {
+	case "completed":
+		w.bus.emit(Connected{Ifname: w.ifname})
+	case "disconnected", "scanning", "inactive":
+		if authFailed(prev, state, reason) {
+			w.bus.emit(AuthFailed{Ifname: w.ifname, State: prev, Reason: reason})
+		}
+	}
+}
+
+// authFailed classifies leaving an authentication state. wpa_supplicant goes
+// back to scanning when it merely didn't find or reach the AP, that's only a
+// failure with a DisconnectReason. Disconnect() while associating isn't one.
+func authFailed(prev, state string, reason int32) bool {
+	switch prev {
+	case "authenticating", "associating", "associated", "4way_handshake", "group_handshake":
+	default:
+		return false
+	}
+	if reason == -3 {
+		// DEAUTH_LEAVING generated locally, e.g. Disconnect()
+		return false
+	}
+	return state != "scanning" || reason != 0
+}
+
 func (w *WPAInterface) eventUpdate(name string, body []interface{}) {
 	switch name {
 	case "fi.w1.wpa_supplicant1.InterfaceRemoved":
 	case "fi.w1.wpa_supplicant1.Interface.PropertiesChanged":
+		if len(body) == 0 {
+			return
+		}
+		props, ok := body[0].(map[string]dbus.Variant)
+		if !ok {
+			return
+		}
+		if bss, found := props["CurrentBSS"]; found {
+			if path, ok := bss.Value().(dbus.ObjectPath); ok {
+				w.updateCurrentBSS(path)
+			}
+		}
+		// the reason first, AuthFailed of the same change carries it
+		if reason, found := props["DisconnectReason"]; found {
+			if value, ok := reason.Value().(int32); ok && value != 0 {
+				w.mu.Lock()
+				w.reason = value
+				w.mu.Unlock()
+				w.bus.emit(Disconnected{Ifname: w.ifname, Reason: value})
+			}
+		}
+		if state, found := props["State"]; found {
+			if value, ok := state.Value().(string); ok {
+				w.updateState(value)
+			}
+		}
+	case SignalEAP:
+		w.updateEAP(body)
 	case "fi.w1.wpa_supplicant1.Interface.ScanDone":
+		if len(body) > 0 {
+			if success, ok := body[0].(bool); ok {
+				w.bus.emit(ScanFinished{Ifname: w.ifname, Success: success})
+			}
+		}
 	}
 }
 
-func (w *WPAInterface) eventListener() {
-	signal := w.bus.GetSignal()
+func (w *WPAInterface) eventListener(signal chan *dbus.Signal) {
+	defer w.bus.Unsubscribe(signal)
 	for {
 		select {
-		case event := <-signal:
-			w.eventUpdate(event.Name, event.Body)
+		case event, ok := <-signal:
+			if !ok {
+				return
+			}
+			if event.Path == w.Path() {
+				w.eventUpdate(event.Name, event.Body)
+			}
 		case <-w.ctx.Done():
 			return
 		}
@@ -344,12 +678,68 @@ func (w *WPAInterface) eventListener() {
 }
 
 func (w *WPAInterface) AddEventListener() error {
-	if w.ifacePath == "" {
+	if w.Path() == "" {
 		return errors.New("interface not ready")
 	}
-	obj := w.bus.Connection.Object("fi.w1.wpa_supplicant1", w.ifacePath)
-	if err := w.bus.AddSignalObserver("fi.w1.wpa_supplicant1.Interface", obj.Path()); err != nil {
-		return err
+	obj := w.bus.object(w.Path())
+	w.mu.Lock()
+	observed := w.observed
+	w.mu.Unlock()
+	if observed != obj.Path() {
+		if err := w.bus.AddSignalObserver("fi.w1.wpa_supplicant1.Interface", obj.Path()); err != nil {
+			return err
+		}
+		if observed != "" {
+			// the path of the previous wpa_supplicant instance
+			w.bus.Signal.RemoveObserver("fi.w1.wpa_supplicant1.Interface", observed)
+		}
+		w.mu.Lock()
+		w.observed = obj.Path()
+		w.mu.Unlock()
+	}
+	var bss dbus.ObjectPath
+	if prop, err := obj.GetProperty("fi.w1.wpa_supplicant1.Interface.CurrentBSS"); err == nil {
+		bss, _ = prop.Value().(dbus.ObjectPath)
+	}
+	bssid, state := w.bssidOf(bss), w.State()
+	w.mu.Lock()
+	w.currentBSS, w.currentBSSID, w.state = bss, bssid, state
+	// the subscription survives bus reconnections, only start it once
+	listen := !w.listening
+	w.listening = true
+	w.mu.Unlock()
+	if listen {
+		go w.eventListener(w.bus.Subscribe())
 	}
 	return nil
 }
+
+// recreate restores the interface after wpa_supplicant restarted, object
+// paths of the previous instance are stale by then.
+func (w *WPAInterface) recreate(reapply bool) error {
+	if err := w.createInterface(w.opts); err != nil {
+		return err
+	}
+	if w.opts.Driver == DriverWired {
+		if err := w.SetApScan(0); err != nil {
+			return err
+		}
+	}
+	if err := w.AddEventListener(); err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	saved := w.saved
+	w.saved = make(map[dbus.ObjectPath]map[string]dbus.Variant)
+	w.mu.Unlock()
+	if reapply {
+		for _, args := range saved {
+			if _, err := w.AddNetwork(args); err != nil {
+				return err
+			}
+		}
+	}
+	_, err := w.GetNetworks()
+	return err
+}
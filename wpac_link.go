@@ -0,0 +1,249 @@
+package wpac
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	EventSignalDegraded  = "SignalDegraded"
+	EventSignalRecovered = "SignalRecovered"
+
+	// SignalBSSPropertiesChanged BSS property updates, Signal is refreshed on every scan/beacon report
+	SignalBSSPropertiesChanged = "fi.w1.wpa_supplicant1.BSS.PropertiesChanged"
+)
+
+// LinkQuality Result of fi.w1.wpa_supplicant1.Interface.SignalPoll
+type LinkQuality struct {
+	RSSI          int32  `json:"rssi"`
+	AvgRSSI       int32  `json:"avg_rssi"`
+	LinkSpeed     int32  `json:"linkspeed"`
+	Noise         int32  `json:"noise"`
+	Frequency     uint32 `json:"frequency"`
+	Width         string `json:"width"`
+	CenterFreq1   int32  `json:"center_frq1"`
+	CenterFreq2   int32  `json:"center_frq2"`
+	TxBitrateKbps uint32 `json:"tx_bitrate"`
+	RxBitrateKbps uint32 `json:"rx_bitrate"`
+}
+
+// SignalMonitorConfig Thresholds are in dBm, Recovered must be above Degraded so
+// the link doesn't flap between both events around a single value.
+// An Interval of 0 follows the Signal updates of the current BSS instead of polling.
+// A Recovered of 0 defaults to Degraded + SignalHysteresis.
+type SignalMonitorConfig struct {
+	Interval  time.Duration
+	Degraded  int32
+	Recovered int32
+}
+
+type SignalDegraded struct {
+	Ifname    string `json:"ifname"`
+	RSSI      int32  `json:"rssi"`
+	Threshold int32  `json:"threshold"`
+}
+
+func (e SignalDegraded) EventName() string {
+	return EventSignalDegraded
+}
+
+type SignalRecovered struct {
+	Ifname    string `json:"ifname"`
+	RSSI      int32  `json:"rssi"`
+	Threshold int32  `json:"threshold"`
+}
+
+func (e SignalRecovered) EventName() string {
+	return EventSignalRecovered
+}
+
+func variantInt64(v dbus.Variant) int64 {
+	switch n := v.Value().(type) {
+	case int16:
+		return int64(n)
+	case uint16:
+		return int64(n)
+	case int32:
+		return int64(n)
+	case uint32:
+		return int64(n)
+	case int64:
+		return n
+	case uint64:
+		return int64(n)
+	}
+	return 0
+}
+
+// SignalPoll Request the current link quality from the driver, fails when not associated.
+func (self *WPAInterface) SignalPoll() (LinkQuality, error) {
+	lq := LinkQuality{}
+	obj := self.bus.object(self.Path())
+	call := obj.Call("fi.w1.wpa_supplicant1.Interface.SignalPoll", 0)
+	if call.Err != nil {
+		return lq, call.Err
+	}
+	if len(call.Body) == 0 {
+		return lq, errors.New("empty signal poll result")
+	}
+	dict, ok := call.Body[0].(map[string]dbus.Variant)
+	if !ok {
+		return lq, errors.New("invalid signal poll result")
+	}
+	for k, v := range dict {
+		switch k {
+		case "rssi":
+			lq.RSSI = int32(variantInt64(v))
+		case "avg-rssi":
+			lq.AvgRSSI = int32(variantInt64(v))
+		case "linkspeed":
+			lq.LinkSpeed = int32(variantInt64(v))
+		case "noise":
+			lq.Noise = int32(variantInt64(v))
+		case "frequency":
+			lq.Frequency = uint32(variantInt64(v))
+		case "width":
+			lq.Width, _ = v.Value().(string)
+		case "center-frq1":
+			lq.CenterFreq1 = int32(variantInt64(v))
+		case "center-frq2":
+			lq.CenterFreq2 = int32(variantInt64(v))
+		case "linktxspeed":
+			lq.TxBitrateKbps = uint32(variantInt64(v))
+		case "linkrxspeed":
+			lq.RxBitrateKbps = uint32(variantInt64(v))
+		}
+	}
+	return lq, nil
+}
+
+// SignalHysteresis dB between the default Recovered and the Degraded threshold
+const SignalHysteresis = 5
+
+type signalThreshold struct {
+	config   SignalMonitorConfig
+	ifname   string
+	degraded bool
+}
+
+func (st *signalThreshold) update(rssi int32) WPAEvent {
+	if !st.degraded && rssi <= st.config.Degraded {
+		st.degraded = true
+		return SignalDegraded{Ifname: st.ifname, RSSI: rssi, Threshold: st.config.Degraded}
+	}
+	if st.degraded && rssi >= st.config.Recovered {
+		st.degraded = false
+		return SignalRecovered{Ifname: st.ifname, RSSI: rssi, Threshold: st.config.Recovered}
+	}
+	return nil
+}
+
+// reset forgets a degraded link once it dropped, the next link starts over
+func (st *signalThreshold) reset() {
+	st.degraded = false
+}
+
+// MonitorSignal Emit SignalDegraded/SignalRecovered events until ctx is done.
+func (self *WPAInterface) MonitorSignal(ctx context.Context, config SignalMonitorConfig) error {
+	if self.Path() == "" {
+		return errors.New("interface not ready")
+	}
+	if config.Recovered == 0 {
+		config.Recovered = config.Degraded + SignalHysteresis
+	}
+	if config.Recovered <= config.Degraded {
+		return errors.New("recovered threshold must be above degraded threshold")
+	}
+	st := &signalThreshold{config: config, ifname: self.ifname}
+	if config.Interval > 0 {
+		go self.pollSignal(ctx, st)
+	} else {
+		go self.watchSignal(ctx, st)
+	}
+	return nil
+}
+
+func (self *WPAInterface) pollSignal(ctx context.Context, st *signalThreshold) {
+	ticker := time.NewTicker(st.config.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			lq, err := self.SignalPoll()
+			if err != nil || lq.RSSI == 0 {
+				// not associated
+				st.reset()
+				continue
+			}
+			if event := st.update(lq.RSSI); event != nil {
+				self.bus.emit(event)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (self *WPAInterface) watchSignal(ctx context.Context, st *signalThreshold) {
+	var bssPath dbus.ObjectPath
+	follow := func(path dbus.ObjectPath) {
+		if path == bssPath {
+			return
+		}
+		if path == "" || path == "/" {
+			st.reset()
+		}
+		if bssPath != "" && bssPath != "/" {
+			self.bus.Signal.RemoveObserver("fi.w1.wpa_supplicant1.BSS", bssPath)
+		}
+		bssPath = path
+		if bssPath != "" && bssPath != "/" {
+			self.bus.AddSignalObserver("fi.w1.wpa_supplicant1.BSS", bssPath)
+		}
+	}
+
+	signal := self.bus.Subscribe()
+	defer self.bus.Unsubscribe(signal)
+	obj := self.bus.object(self.Path())
+	if prop, err := obj.GetProperty("fi.w1.wpa_supplicant1.Interface.CurrentBSS"); err == nil {
+		if path, ok := prop.Value().(dbus.ObjectPath); ok {
+			follow(path)
+		}
+	}
+	defer follow("")
+
+	for {
+		select {
+		case event, ok := <-signal:
+			if !ok {
+				return
+			}
+			if len(event.Body) == 0 {
+				continue
+			}
+			props, ok := event.Body[0].(map[string]dbus.Variant)
+			if !ok {
+				continue
+			}
+			switch {
+			case event.Name == SignalPropertiesChanged && event.Path == self.Path():
+				if bss, found := props["CurrentBSS"]; found {
+					if path, ok := bss.Value().(dbus.ObjectPath); ok {
+						follow(path)
+					}
+				}
+			case event.Name == SignalBSSPropertiesChanged && event.Path == bssPath:
+				if rssi, found := props["Signal"]; found {
+					if e := st.update(int32(variantInt64(rssi))); e != nil {
+						self.bus.emit(e)
+					}
+				}
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -18,29 +18,33 @@ const (
 	WPANetworkPairWise = "pairwise"
 	WPANetworkGroup    = "group"
 	WPANetworkPriority = "priority"
+	WPANetworkBGScan   = "bgscan"
 )
 
 // WPABSS ...
 type WPANetwork struct {
 	busObject dbus.BusObject
-	Object    dbus.ObjectPath
-	Enable    bool
-	ID        int
-	BSSID     string
-	SSID      string
-	PSK       string
-	KeyMgmt   string
-	Proto     string
-	Mode      string
-	PairWise  string
-	Group     string
-	Frequency uint16
-	Priority  int64
+	Object    dbus.ObjectPath `json:"path"`
+	Enable    bool            `json:"enabled"`
+	ID        int             `json:"id"`
+	BSSID     string          `json:"bssid"`
+	SSID      string          `json:"ssid"`
+	PSK       Secret          `json:"psk"`
+	KeyMgmt   string          `json:"key_mgmt"`
+	Proto     string          `json:"proto"`
+	Mode      string          `json:"mode"`
+	PairWise  string          `json:"pairwise"`
+	Group     string          `json:"group"`
+	Frequency uint16          `json:"frequency"`
+	Priority  int64           `json:"priority"`
+	BGScan    string          `json:"bgscan"`
+	// props every field wpa_supplicant reports, unquoted, keys are never included
+	props map[string]string
 }
 
 // NewNetwork ...
 func NewWPANetwork(bus *WPADBus, objPath dbus.ObjectPath) WPANetwork {
-	obj := bus.Connection.Object("fi.w1.wpa_supplicant1", objPath)
+	obj := bus.object(objPath)
 	s := strings.Split(string(objPath), "/")
 	ID, _ := strconv.Atoi(s[len(s)-1])
 	network := WPANetwork{busObject: obj, Object: objPath, ID: ID}
@@ -75,15 +79,17 @@ func (wn *WPANetwork) readProp() error {
 	if err == nil {
 		re := regexp.MustCompile(`^"(.*)"$`)
 		if dict, ok := prop.Value().(map[string]dbus.Variant); ok {
+			wn.props = make(map[string]string, len(dict))
 			for k, v := range dict {
 				value = re.ReplaceAllString(v.Value().(string), `$1`)
+				wn.props[k] = value
 				switch k {
 				case WPANetworkSSID:
 					wn.SSID = value
 				case WPANetworkBSSID:
 					wn.BSSID = value
 				case WPANetworkPSK:
-					wn.PSK = value
+					wn.PSK = Secret(value)
 				case WPANetworkKeyMgmt:
 					wn.KeyMgmt = value
 				case WPANetworkProto:
@@ -96,6 +102,8 @@ func (wn *WPANetwork) readProp() error {
 					wn.Group = value
 				case WPANetworkPriority:
 					wn.Priority, _ = strconv.ParseInt(value, 0, 64)
+				case WPANetworkBGScan:
+					wn.BGScan = value
 				}
 			}
 		}
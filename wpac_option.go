@@ -0,0 +1,93 @@
+package wpac
+
+import (
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// Option configures NewWPA
+type Option func(*options)
+
+type options struct {
+	activate bool
+	wait     bool
+	timeout  time.Duration
+	conn     *dbus.Conn
+	dial     func() (*dbus.Conn, error)
+	secrets  SecretStore
+	profiles *ProfileStore
+	conflict ConflictPolicy
+}
+
+// WithActivation starts wpa_supplicant through D-Bus activation
+// (StartServiceByName) when it isn't on the bus yet.
+func WithActivation() Option {
+	return func(o *options) {
+		o.activate = true
+	}
+}
+
+// WithWaitForService waits up to timeout for wpa_supplicant to appear on the
+// bus, a timeout of 0 waits until the context given to NewWPA is done.
+func WithWaitForService(timeout time.Duration) Option {
+	return func(o *options) {
+		o.wait = true
+		o.timeout = timeout
+	}
+}
+
+// WithConnection uses an existing connection, wpac never closes it
+// and can't re-establish it if the bus goes away.
+func WithConnection(conn *dbus.Conn) Option {
+	return func(o *options) {
+		o.conn = conn
+		o.dial = nil
+	}
+}
+
+// WithBusAddress connects to a non-default bus socket, e.g.
+// "unix:path=/run/wpa/system_bus_socket" when running in a container.
+func WithBusAddress(address string) Option {
+	return func(o *options) {
+		o.conn = nil
+		o.dial = func() (*dbus.Conn, error) {
+			return dialBus(func() (*dbus.Conn, error) { return dbus.Dial(address) })
+		}
+	}
+}
+
+// WithPrivateConnection uses a dedicated system bus connection instead of
+// the one shared by the process through dbus.SystemBus.
+func WithPrivateConnection() Option {
+	return func(o *options) {
+		o.conn = nil
+		o.dial = dialSystemBus
+	}
+}
+
+// WithSessionBus talks to a wpa_supplicant on the session bus, e.g. for testing.
+func WithSessionBus() Option {
+	return func(o *options) {
+		o.conn = nil
+		o.dial = func() (*dbus.Conn, error) {
+			return dialBus(func() (*dbus.Conn, error) { return dbus.SessionBusPrivate() })
+		}
+	}
+}
+
+// WithSecretStore resolves the SecretRef values of network profiles through store
+func WithSecretStore(store SecretStore) Option {
+	return func(o *options) {
+		o.secrets = store
+	}
+}
+
+// WithProfileStore syncs the profiles of store into every interface when it's
+// attached and when wpa_supplicant comes back after a restart.
+func WithProfileStore(store *ProfileStore, policy ConflictPolicy) Option {
+	return func(o *options) {
+		o.profiles = store
+		o.conflict = policy
+	}
+}
@@ -0,0 +1,118 @@
+package wpac
+
+import (
+	"net"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	OWEGroup19 = 19 // NIST P-256, mandatory
+	OWEGroup20 = 20 // NIST P-384
+	OWEGroup21 = 21 // NIST P-521
+
+	ieVendorSpecific = 221
+	oweTransitionOUI = "\x50\x6f\x9a\x1c"
+)
+
+// OWETransition The OWE Transition Mode element, an open BSS points to the
+// hidden OWE BSS of the same network and the OWE BSS points back.
+type OWETransition struct {
+	BSSID string `json:"bssid"`
+	SSID  string `json:"ssid"`
+}
+
+// IsOWE reports whether the BSS uses Enhanced Open
+func (wb WPABSS) IsOWE() bool {
+	return wb.WPA2 != nil && contains(wb.WPA2.KeyMgmt, "owe")
+}
+
+// IsOWETransition reports whether an open BSS has an OWE companion, an OWE
+// profile with its SSID connects to the companion.
+func (wb WPABSS) IsOWETransition() bool {
+	return wb.OWETransition != nil && !wb.IsOWE()
+}
+
+func (wb *WPABSS) readIEs() error {
+	prop, err := wb.busObject.GetProperty("fi.w1.wpa_supplicant1.BSS.IEs")
+	if err != nil {
+		return err
+	}
+	if ies, ok := prop.Value().([]byte); ok {
+		wb.OWETransition = parseOWETransition(ies)
+	}
+	return nil
+}
+
+// parseOWETransition walks the information elements for the WFA vendor
+// element: OUI 50:6f:9a type 0x1c, BSSID, SSID length, SSID, [band, channel]
+func parseOWETransition(ies []byte) *OWETransition {
+	for len(ies) >= 2 {
+		id, size := ies[0], int(ies[1])
+		if len(ies) < 2+size {
+			return nil
+		}
+		body := ies[2 : 2+size]
+		ies = ies[2+size:]
+		if id != ieVendorSpecific || len(body) < len(oweTransitionOUI)+7 ||
+			string(body[:len(oweTransitionOUI)]) != oweTransitionOUI {
+			continue
+		}
+		body = body[len(oweTransitionOUI):]
+		ssidLen := int(body[6])
+		if len(body) < 7+ssidLen {
+			return nil
+		}
+		return &OWETransition{
+			BSSID: net.HardwareAddr(body[:6]).String(),
+			SSID:  string(body[7 : 7+ssidLen]),
+		}
+	}
+	return nil
+}
+
+// resolveOWETransition names the hidden OWE BSSs after the SSID their open
+// companion advertises
+func resolveOWETransition(bsss []WPABSS) {
+	hidden := make(map[string]string)
+	for _, bss := range bsss {
+		if bss.IsOWETransition() {
+			hidden[bss.OWETransition.BSSID] = bss.OWETransition.SSID
+		}
+	}
+	for i := range bsss {
+		if ssid, found := hidden[bsss[i].BSSID]; found && bsss[i].SSID == "" {
+			bsss[i].SSID = ssid
+		}
+	}
+}
+
+// GetOWE Enhanced Open network, PMF is mandatory. For a transition mode
+// network use the SSID of the open BSS, wpa_supplicant follows the transition
+// element to the hidden OWE BSS.
+func (config *WPASupplicantConfig) GetOWE(bss WPABSS) map[string]dbus.Variant {
+	template := make(map[string]dbus.Variant)
+	template["ssid"] = dbus.MakeVariant(bss.SSID)
+	template["proto"] = dbus.MakeVariant("RSN")
+	template["pairwise"] = dbus.MakeVariant("CCMP")
+	template["group"] = dbus.MakeVariant("CCMP")
+	template["key_mgmt"] = dbus.MakeVariant("OWE")
+	template["ieee80211w"] = dbus.MakeVariant(int32(2))
+	return template
+}
+
+// SetOWEGroup pins the DH group of an OWE template, 0 lets wpa_supplicant try 19, 20 and 21
+func (config *WPASupplicantConfig) SetOWEGroup(template map[string]dbus.Variant, group int) map[string]dbus.Variant {
+	template["owe_group"] = dbus.MakeVariant(int32(group))
+	return template
+}
+
+// SetOWEOnly keeps wpa_supplicant from falling back to the open BSS of a transition mode network
+func (config *WPASupplicantConfig) SetOWEOnly(template map[string]dbus.Variant, only bool) map[string]dbus.Variant {
+	if only {
+		template["owe_only"] = dbus.MakeVariant(int32(1))
+	} else {
+		template["owe_only"] = dbus.MakeVariant(int32(0))
+	}
+	return template
+}
@@ -0,0 +1,73 @@
+package wpac
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+// IEs of an OWE transition pair: the open BSS 02:00:00:00:01:00 "guest" with
+// WMM, and its hidden OWE companion 02:00:00:00:01:01 "guest-owe"
+const (
+	openIEs = "0005" + "6775657374" +
+		"0108" + "82848b960c121824" +
+		"030106" +
+		"dd18" + "0050f202010100" + "0003a4000027a4000042435e0062322f00" +
+		"dd14" + "506f9a1c" + "020000000101" + "09" + "67756573742d6f7765"
+	hiddenIEs = "0000" +
+		"3014" + "0100" + "000fac04" + "0100000fac04" + "0100000fac12" + "c000" +
+		"dd10" + "506f9a1c" + "020000000100" + "05" + "6775657374"
+)
+
+func mustHex(t *testing.T, s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestParseOWETransition(t *testing.T) {
+	tests := []struct {
+		name  string
+		ies   string
+		bssid string
+		ssid  string
+	}{
+		{"open bss", openIEs, "02:00:00:00:01:01", "guest-owe"},
+		{"hidden owe bss", hiddenIEs, "02:00:00:00:01:00", "guest"},
+		{"no transition element", "0005677565737401088284", "", ""},
+		{"empty", "", "", ""},
+		{"truncated element", openIEs[:len(openIEs)-6], "", ""},
+		{"ssid beyond element", "dd0b" + "506f9a1c" + "020000000101" + "09", "", ""},
+		{"other vendor element", "dd0b" + "0050f2040203040506" + "0708", "", ""},
+	}
+	for _, tt := range tests {
+		owe := parseOWETransition(mustHex(t, tt.ies))
+		if tt.bssid == "" {
+			if owe != nil {
+				t.Errorf("%s: parsed %+v, want none", tt.name, *owe)
+			}
+			continue
+		}
+		if owe == nil || owe.BSSID != tt.bssid || owe.SSID != tt.ssid {
+			t.Errorf("%s: parsed %+v, want %s %s", tt.name, owe, tt.bssid, tt.ssid)
+		}
+	}
+}
+
+func TestResolveOWETransition(t *testing.T) {
+	bsss := []WPABSS{
+		{BSSID: "02:00:00:00:01:00", SSID: "guest", OWETransition: parseOWETransition(mustHex(t, openIEs))},
+		{BSSID: "02:00:00:00:01:01", WPA2: &BSSWPA2{KeyMgmt: []string{"owe"}}, OWETransition: parseOWETransition(mustHex(t, hiddenIEs))},
+		{BSSID: "02:00:00:00:02:00", SSID: "other"},
+	}
+	resolveOWETransition(bsss)
+	got := []string{}
+	for _, bss := range bsss {
+		got = append(got, bss.SSID)
+	}
+	if strings.Join(got, ",") != "guest,guest-owe,other" {
+		t.Errorf("resolved ssids %v, want [guest guest-owe other]", got)
+	}
+}
@@ -0,0 +1,213 @@
+package wpac
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"net"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// GetFTPSK WPA2-PSK network with fast BSS transition (802.11r), falls back
+// to plain WPA-PSK on APs without FT.
+func (config *WPASupplicantConfig) GetFTPSK(bss WPABSS) (map[string]dbus.Variant, error) {
+	template, err := config.GetWPA2PSK(bss)
+	if err != nil {
+		return nil, err
+	}
+	template["key_mgmt"] = dbus.MakeVariant("FT-PSK WPA-PSK")
+	return template, nil
+}
+
+// GetFTEAP WPA2-Enterprise network with fast BSS transition
+func (config *WPASupplicantConfig) GetFTEAP(eap EAPConfig) map[string]dbus.Variant {
+	template := config.GetWPA2EAP(eap)
+	template["key_mgmt"] = dbus.MakeVariant("FT-EAP WPA-EAP")
+	return template
+}
+
+// GetFTSAE WPA3-Personal network with fast BSS transition. SAE can't use a
+// derived PSK, the passphrase (or PSKRef) goes to wpa_supplicant as sae_password.
+func (config *WPASupplicantConfig) GetFTSAE(bss WPABSS) map[string]dbus.Variant {
+	template := make(map[string]dbus.Variant)
+	template["ssid"] = dbus.MakeVariant(bss.SSID)
+	template["proto"] = dbus.MakeVariant("RSN")
+	template["pairwise"] = dbus.MakeVariant("CCMP")
+	template["group"] = dbus.MakeVariant("CCMP")
+	template["key_mgmt"] = dbus.MakeVariant("FT-SAE SAE")
+	template["ieee80211w"] = dbus.MakeVariant(int32(2))
+	if bss.PSKRef != "" {
+		template["sae_password"] = dbus.MakeVariant(SecretRef(bss.PSKRef))
+	} else if bss.PSK != "" {
+		template["sae_password"] = dbus.MakeVariant(bss.PSK.Reveal())
+	}
+	return template
+}
+
+// SetProactiveKeyCaching enables opportunistic PMKSA caching (OKC) of a WPA2-EAP template
+func (config *WPASupplicantConfig) SetProactiveKeyCaching(template map[string]dbus.Variant, enable bool) map[string]dbus.Variant {
+	if enable {
+		template["proactive_key_caching"] = dbus.MakeVariant(int32(1))
+	} else {
+		template["proactive_key_caching"] = dbus.MakeVariant(int32(0))
+	}
+	return template
+}
+
+// PMKSAEntry A PMKSA cache entry, PMK is hex and redacted like any Secret
+type PMKSAEntry struct {
+	BSSID              string `json:"bssid"`
+	PMKID              string `json:"pmkid"`
+	PMK                Secret `json:"pmk"`
+	Expiration         int64  `json:"expiration"`
+	KeyMgmt            uint32 `json:"key_mgmt"`
+	Opportunistic      uint32 `json:"opportunistic"`
+	PMKLifetime        uint32 `json:"pmk_lifetime"`
+	PMKReauthThreshold uint32 `json:"pmk_reauth_threshold"`
+}
+
+func newPMKSAEntry(dict map[string]dbus.Variant) PMKSAEntry {
+	entry := PMKSAEntry{}
+	for k, v := range dict {
+		switch value := v.Value().(type) {
+		case []byte:
+			switch k {
+			case "bssid":
+				entry.BSSID = net.HardwareAddr(value).String()
+			case "pmkid":
+				entry.PMKID = hex.EncodeToString(value)
+			case "pmk":
+				entry.PMK = Secret(hex.EncodeToString(value))
+			}
+		case int64:
+			if k == "expiration" {
+				entry.Expiration = value
+			}
+		case uint32:
+			switch k {
+			case "key_mgmt":
+				entry.KeyMgmt = value
+			case "opportunistic":
+				entry.Opportunistic = value
+			case "pmk_lifetime":
+				entry.PMKLifetime = value
+			case "pmk_reauth_threshold":
+				entry.PMKReauthThreshold = value
+			}
+		}
+	}
+	return entry
+}
+
+func (e PMKSAEntry) args() (map[string]dbus.Variant, error) {
+	bssid, err := net.ParseMAC(e.BSSID)
+	if err != nil {
+		return nil, err
+	}
+	pmkid, err := hex.DecodeString(e.PMKID)
+	if err != nil {
+		return nil, err
+	}
+	pmk, err := hex.DecodeString(e.PMK.Reveal())
+	if err != nil {
+		return nil, err
+	}
+	return map[string]dbus.Variant{
+		"bssid":                dbus.MakeVariant([]byte(bssid)),
+		"pmkid":                dbus.MakeVariant(pmkid),
+		"pmk":                  dbus.MakeVariant(pmk),
+		"expiration":           dbus.MakeVariant(e.Expiration),
+		"key_mgmt":             dbus.MakeVariant(e.KeyMgmt),
+		"opportunistic":        dbus.MakeVariant(e.Opportunistic),
+		"pmk_lifetime":         dbus.MakeVariant(e.PMKLifetime),
+		"pmk_reauth_threshold": dbus.MakeVariant(e.PMKReauthThreshold),
+	}, nil
+}
+
+// FlushBSS drops the BSSs not seen for age seconds, 0 drops all but the current one
+func (self *WPAInterface) FlushBSS(age uint32) error {
+	obj := self.bus.object(self.Path())
+	if call := obj.Call("fi.w1.wpa_supplicant1.Interface.FlushBSS", 0, age); call.Err != nil {
+		return call.Err
+	}
+	return nil
+}
+
+func (self *WPAInterface) PMKSAFlush() error {
+	obj := self.bus.object(self.Path())
+	if call := obj.Call("fi.w1.wpa_supplicant1.Interface.PMKSAFlush", 0); call.Err != nil {
+		return call.Err
+	}
+	return nil
+}
+
+// GetPMKSA lists the PMKSA cache of the current network, wpa_supplicant must
+// be built with CONFIG_PMKSA_CACHE_EXTERNAL.
+func (self *WPAInterface) GetPMKSA() ([]PMKSAEntry, error) {
+	var dicts []map[string]dbus.Variant
+	obj := self.bus.object(self.Path())
+	if err := obj.Call("fi.w1.wpa_supplicant1.Interface.PMKSAGet", 0).Store(&dicts); err != nil {
+		return nil, err
+	}
+	entries := make([]PMKSAEntry, 0, len(dicts))
+	for _, dict := range dicts {
+		entries = append(entries, newPMKSAEntry(dict))
+	}
+	return entries, nil
+}
+
+// AddPMKSA restores an entry of GetPMKSA to the cache of the current network
+func (self *WPAInterface) AddPMKSA(entry PMKSAEntry) error {
+	args, err := entry.args()
+	if err != nil {
+		return err
+	}
+	obj := self.bus.object(self.Path())
+	if call := obj.Call("fi.w1.wpa_supplicant1.Interface.PMKSAAdd", 0, args); call.Err != nil {
+		return call.Err
+	}
+	return nil
+}
+
+// pmksaRecord keeps the PMK in clear text for SavePMKSA, PMKSAEntry would redact it
+type pmksaRecord struct {
+	PMKSAEntry
+	PMK string `json:"pmk"`
+}
+
+// SavePMKSA persists the PMKSA cache in store under ref, so re-authentication
+// after a restart skips the full EAP exchange.
+func (self *WPAInterface) SavePMKSA(store SecretStore, ref string) error {
+	entries, err := self.GetPMKSA()
+	if err != nil {
+		return err
+	}
+	records := make([]pmksaRecord, 0, len(entries))
+	for _, entry := range entries {
+		records = append(records, pmksaRecord{PMKSAEntry: entry, PMK: entry.PMK.Reveal()})
+	}
+	data, err := json.Marshal(records)
+	if err != nil {
+		return err
+	}
+	return store.Put(ref, Secret(data))
+}
+
+// RestorePMKSA adds the entries saved by SavePMKSA
+func (self *WPAInterface) RestorePMKSA(store SecretStore, ref string) error {
+	data, err := store.Get(ref)
+	if err != nil {
+		return err
+	}
+	records := []pmksaRecord{}
+	if err := json.Unmarshal([]byte(data.Reveal()), &records); err != nil {
+		return err
+	}
+	for _, record := range records {
+		record.PMKSAEntry.PMK = Secret(record.PMK)
+		if err := self.AddPMKSA(record.PMKSAEntry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
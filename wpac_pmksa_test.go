@@ -0,0 +1,100 @@
+package wpac
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/godbus/dbus/v5"
+)
+
+func TestFTTemplates(t *testing.T) {
+	bss := WPABSS{SSID: "office", PSK: "password"}
+	ftpsk, err := WPAConfig().GetFTPSK(bss)
+	if err != nil {
+		t.Fatal(err)
+	}
+	eap := EAPConfig{SSID: "corp", Method: EAPMethodPEAP, Identity: "user", Password: "secret"}
+	tests := []struct {
+		name       string
+		template   map[string]dbus.Variant
+		keyMgmt    string
+		pkc        interface{}
+		ieee80211w interface{}
+	}{
+		{"ft-psk", ftpsk, "FT-PSK WPA-PSK", nil, nil},
+		{"ft-eap", WPAConfig().GetFTEAP(eap), "FT-EAP WPA-EAP", nil, nil},
+		{"ft-eap okc", WPAConfig().SetProactiveKeyCaching(WPAConfig().GetFTEAP(eap), true), "FT-EAP WPA-EAP", int32(1), nil},
+		{"ft-eap no okc", WPAConfig().SetProactiveKeyCaching(WPAConfig().GetFTEAP(eap), false), "FT-EAP WPA-EAP", int32(0), nil},
+		{"ft-sae", WPAConfig().GetFTSAE(bss), "FT-SAE SAE", nil, int32(2)},
+	}
+	for _, tt := range tests {
+		if keyMgmt := tt.template["key_mgmt"].Value(); keyMgmt != tt.keyMgmt {
+			t.Errorf("%s: key_mgmt %v, want %s", tt.name, keyMgmt, tt.keyMgmt)
+		}
+		// wpa_supplicant quotes strings, integer fields have to be int32
+		if pkc := tt.template["proactive_key_caching"].Value(); !reflect.DeepEqual(pkc, tt.pkc) {
+			t.Errorf("%s: proactive_key_caching %#v, want %#v", tt.name, pkc, tt.pkc)
+		}
+		if pmf := tt.template["ieee80211w"].Value(); !reflect.DeepEqual(pmf, tt.ieee80211w) {
+			t.Errorf("%s: ieee80211w %#v, want %#v", tt.name, pmf, tt.ieee80211w)
+		}
+	}
+}
+
+func TestNewPMKSAEntry(t *testing.T) {
+	bssid := []byte{0x00, 0x11, 0x22, 0x33, 0x44, 0x55}
+	pmkid := []byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f, 0x10}
+	tests := []struct {
+		name string
+		dict map[string]dbus.Variant
+		want PMKSAEntry
+	}{
+		{
+			"complete",
+			map[string]dbus.Variant{
+				"bssid":                dbus.MakeVariant(bssid),
+				"pmkid":                dbus.MakeVariant(pmkid),
+				"pmk":                  dbus.MakeVariant([]byte{0xde, 0xad, 0xbe, 0xef}),
+				"expiration":           dbus.MakeVariant(int64(1700000000)),
+				"key_mgmt":             dbus.MakeVariant(uint32(0x2000)),
+				"opportunistic":        dbus.MakeVariant(uint32(1)),
+				"pmk_lifetime":         dbus.MakeVariant(uint32(43200)),
+				"pmk_reauth_threshold": dbus.MakeVariant(uint32(70)),
+			},
+			PMKSAEntry{
+				BSSID:              "00:11:22:33:44:55",
+				PMKID:              "0102030405060708090a0b0c0d0e0f10",
+				PMK:                "deadbeef",
+				Expiration:         1700000000,
+				KeyMgmt:            0x2000,
+				Opportunistic:      1,
+				PMKLifetime:        43200,
+				PMKReauthThreshold: 70,
+			},
+		},
+		{
+			"wrong types are skipped",
+			map[string]dbus.Variant{
+				"bssid":      dbus.MakeVariant("00:11:22:33:44:55"),
+				"expiration": dbus.MakeVariant(int32(5)),
+				"key_mgmt":   dbus.MakeVariant(int64(2)),
+			},
+			PMKSAEntry{},
+		},
+		{"empty", map[string]dbus.Variant{}, PMKSAEntry{}},
+	}
+	for _, tt := range tests {
+		if entry := newPMKSAEntry(tt.dict); entry != tt.want {
+			t.Errorf("%s: newPMKSAEntry = %+v, want %+v", tt.name, entry, tt.want)
+		}
+	}
+
+	// AddPMKSA sends an entry the way PMKSAGet reported it
+	args, err := tests[0].want.args()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(args, tests[0].dict) {
+		t.Errorf("args() = %v, want %v", args, tests[0].dict)
+	}
+}
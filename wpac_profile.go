@@ -0,0 +1,302 @@
+package wpac
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+var (
+	ErrProfileExists   = errors.New("profile already exists")
+	ErrProfileNotFound = errors.New("profile not found")
+)
+
+// Profile A network as the application keeps it. Settings hold the
+// wpa_supplicant network fields, e.g. "ssid", "key_mgmt", "priority", and
+// Secrets map fields like "psk" or "password" to SecretStore references.
+type Profile struct {
+	Name     string                 `json:"name"`
+	Settings map[string]interface{} `json:"settings"`
+	Secrets  map[string]string      `json:"secrets,omitempty"`
+	Updated  time.Time              `json:"updated"`
+}
+
+// NewProfile converts a network template, e.g. from WPAConfig().GetWPA2, into a
+// profile. Secret fields (psk, passwords, WEP keys) are moved into store under
+// "<name>/<field>" and kept as references, so the profile never holds the
+// clear text. A derived psk and hex WEP keys are stored as hex.
+func NewProfile(name string, args map[string]dbus.Variant, store SecretStore) (Profile, error) {
+	p := Profile{Name: name, Settings: make(map[string]interface{})}
+	if p.Name == "" {
+		if v, ok := args[WPANetworkSSID]; ok {
+			p.Name = variantSSID(v)
+		}
+	}
+	for k, v := range args {
+		if ref, ok := v.Value().(SecretRef); ok {
+			p.addSecret(k, string(ref))
+			continue
+		}
+		if secretFields[k] {
+			secret, err := secretOf(k, v)
+			if err != nil {
+				return Profile{}, err
+			}
+			if store == nil {
+				return Profile{}, fmt.Errorf("%w (%s of profile %s)", ErrNoSecretStore, k, p.Name)
+			}
+			ref := p.Name + "/" + k
+			if err := store.Put(ref, secret); err != nil {
+				return Profile{}, err
+			}
+			p.addSecret(k, ref)
+			continue
+		}
+		switch value := v.Value().(type) {
+		case []byte:
+			p.Settings[k] = string(value)
+		case bool:
+			if value {
+				p.Settings[k] = 1
+			} else {
+				p.Settings[k] = 0
+			}
+		default:
+			p.Settings[k] = value
+		}
+	}
+	return p, nil
+}
+
+func (p *Profile) addSecret(field, ref string) {
+	if p.Secrets == nil {
+		p.Secrets = make(map[string]string)
+	}
+	p.Secrets[field] = ref
+}
+
+// secretOf the value of a secret field as it goes into a SecretStore, raw
+// keys as hex which resolveSecrets turns back into bytes
+func secretOf(field string, v dbus.Variant) (Secret, error) {
+	switch value := v.Value().(type) {
+	case []byte:
+		return Secret(hex.EncodeToString(value)), nil
+	case Secret:
+		return value, nil
+	case string:
+		return Secret(value), nil
+	}
+	return "", fmt.Errorf("unsupported %s value %T", field, v.Value())
+}
+
+func isWEPKey(field string) bool {
+	switch field {
+	case "wep_key0", "wep_key1", "wep_key2", "wep_key3":
+		return true
+	}
+	return false
+}
+
+// SSID identifies the profile among the networks of wpa_supplicant
+func (p Profile) SSID() string {
+	if ssid, ok := p.Settings[WPANetworkSSID].(string); ok {
+		return ssid
+	}
+	return ""
+}
+
+// Args builds the AddNetwork arguments, numbers are sent as int32 since
+// wpa_supplicant would quote strings of integer fields.
+func (p Profile) Args() map[string]dbus.Variant {
+	args := make(map[string]dbus.Variant, len(p.Settings)+len(p.Secrets))
+	for k, v := range p.Settings {
+		switch value := v.(type) {
+		case float64:
+			args[k] = dbus.MakeVariant(int32(value))
+		case int:
+			args[k] = dbus.MakeVariant(int32(value))
+		case int64:
+			args[k] = dbus.MakeVariant(int32(value))
+		case string:
+			if k == WPANetworkPSK && len(value) == 2*pskLength {
+				if psk, err := hex.DecodeString(value); err == nil {
+					args[k] = dbus.MakeVariant(psk)
+					continue
+				}
+			}
+			if isWEPKey(k) && (len(value) == 10 || len(value) == 26) {
+				// hex WEP keys, ASCII ones have 5 or 13 characters
+				if key, err := hex.DecodeString(value); err == nil {
+					args[k] = dbus.MakeVariant(key)
+					continue
+				}
+			}
+			args[k] = dbus.MakeVariant(value)
+		default:
+			args[k] = dbus.MakeVariant(value)
+		}
+	}
+	for k, ref := range p.Secrets {
+		args[k] = dbus.MakeVariant(SecretRef(ref))
+	}
+	return args
+}
+
+// ProfileStore keeps profiles in a JSON file, keyed by name
+type ProfileStore struct {
+	mu       sync.Mutex
+	path     string
+	profiles map[string]Profile
+}
+
+// OpenProfileStore loads the store at path, which is created on the first change
+func OpenProfileStore(path string) (*ProfileStore, error) {
+	ps := &ProfileStore{path: path, profiles: make(map[string]Profile)}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return ps, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var profiles []Profile
+	if err := json.Unmarshal(data, &profiles); err != nil {
+		return nil, fmt.Errorf("invalid profile store %s (%s)", path, err.Error())
+	}
+	for _, p := range profiles {
+		ps.profiles[p.Name] = p
+	}
+	return ps, nil
+}
+
+func (ps *ProfileStore) Add(p Profile) error {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	if _, found := ps.profiles[p.Name]; found {
+		return fmt.Errorf("%w (%s)", ErrProfileExists, p.Name)
+	}
+	p.Updated = time.Now()
+	ps.profiles[p.Name] = p
+	return ps.save()
+}
+
+func (ps *ProfileStore) Update(p Profile) error {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	if _, found := ps.profiles[p.Name]; !found {
+		return fmt.Errorf("%w (%s)", ErrProfileNotFound, p.Name)
+	}
+	p.Updated = time.Now()
+	ps.profiles[p.Name] = p
+	return ps.save()
+}
+
+func (ps *ProfileStore) Delete(name string) error {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	if _, found := ps.profiles[name]; !found {
+		return fmt.Errorf("%w (%s)", ErrProfileNotFound, name)
+	}
+	delete(ps.profiles, name)
+	return ps.save()
+}
+
+func (ps *ProfileStore) Get(name string) (Profile, error) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	p, found := ps.profiles[name]
+	if !found {
+		return Profile{}, fmt.Errorf("%w (%s)", ErrProfileNotFound, name)
+	}
+	return p, nil
+}
+
+// List returns the profiles sorted by name
+func (ps *ProfileStore) List() []Profile {
+	ps.mu.Lock()
+	profiles := make([]Profile, 0, len(ps.profiles))
+	for _, p := range ps.profiles {
+		profiles = append(profiles, p)
+	}
+	ps.mu.Unlock()
+	sort.Slice(profiles, func(i, j int) bool { return profiles[i].Name < profiles[j].Name })
+	return profiles
+}
+
+// save replaces the file atomically, the caller holds ps.mu
+func (ps *ProfileStore) save() error {
+	profiles := make([]Profile, 0, len(ps.profiles))
+	for _, p := range ps.profiles {
+		profiles = append(profiles, p)
+	}
+	sort.Slice(profiles, func(i, j int) bool { return profiles[i].Name < profiles[j].Name })
+	data, err := json.MarshalIndent(profiles, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(ps.path, data, 0600)
+}
+
+// ConflictPolicy decides what SyncProfiles does with a supplicant network
+// which has the SSID of a profile
+type ConflictPolicy int
+
+const (
+	// ConflictPreferStore overwrites the supplicant network with the profile
+	ConflictPreferStore ConflictPolicy = iota
+	// ConflictPreferSupplicant leaves the supplicant network as it is
+	ConflictPreferSupplicant
+	// ConflictReplace removes the supplicant network and adds the profile
+	ConflictReplace
+)
+
+// SyncProfiles writes the profiles of store into wpa_supplicant. Networks
+// without a profile are left alone.
+func (self *WPAInterface) SyncProfiles(store *ProfileStore, policy ConflictPolicy) error {
+	networks, err := self.GetNetworks()
+	if err != nil {
+		return err
+	}
+	bySSID := make(map[string]int, len(networks))
+	for id, network := range networks {
+		bySSID[network.SSID] = id
+	}
+	for _, p := range store.List() {
+		id, found := bySSID[p.SSID()]
+		if !found {
+			if _, err := self.AddNetwork(p.Args()); err != nil {
+				return fmt.Errorf("profile %s: %s", p.Name, err.Error())
+			}
+			continue
+		}
+		switch policy {
+		case ConflictPreferStore:
+			err = self.SetNetwork(id, p.Args())
+		case ConflictReplace:
+			if err = self.RemoveNetwork(id); err == nil {
+				_, err = self.AddNetwork(p.Args())
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("profile %s: %s", p.Name, err.Error())
+		}
+	}
+	return nil
+}
+
+// SaveConfig writes the networks to the configuration file of the interface,
+// wpa_supplicant must run with update_config=1.
+func (self *WPAInterface) SaveConfig() error {
+	obj := self.bus.object(self.Path())
+	if call := obj.Call("fi.w1.wpa_supplicant1.Interface.SaveConfig", 0); call.Err != nil {
+		return call.Err
+	}
+	return nil
+}
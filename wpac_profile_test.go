@@ -0,0 +1,77 @@
+package wpac
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/godbus/dbus/v5"
+)
+
+func TestProfileJSONRoundTrip(t *testing.T) {
+	wep, err := WPAConfig().GetWEP(WEPConfig{
+		SSID: "legacy",
+		Keys: [4]Secret{"0102030405", "abcde", "0102030405060708090a0b0c0d"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wpa2, err := WPAConfig().GetWPA2PSK(WPABSS{SSID: "IEEE", PSK: "password"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		args map[string]dbus.Variant
+	}{
+		{"wep", wep},
+		{"wpa2", wpa2},
+	}
+	store := NewMemorySecretStore()
+	for _, tt := range tests {
+		profile, err := NewProfile(tt.name, tt.args, store)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := json.Marshal(profile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		p := Profile{}
+		if err := json.Unmarshal(data, &p); err != nil {
+			t.Fatal(err)
+		}
+		for k := range p.Settings {
+			if secretFields[k] {
+				t.Errorf("%s: secret %s kept in the settings", tt.name, k)
+			}
+		}
+		args, err := resolveSecrets(store, p.Args(), "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		for k, v := range tt.args {
+			want := v.Value()
+			if b, ok := want.(bool); ok {
+				want = int32(0)
+				if b {
+					want = int32(1)
+				}
+			}
+			if got, found := args[k]; !found || !reflect.DeepEqual(got.Value(), want) {
+				t.Errorf("%s: %s = %#v after a JSON round trip, want %#v", tt.name, k, args[k].Value(), want)
+			}
+		}
+	}
+}
+
+func TestNewProfileNeedsSecretStore(t *testing.T) {
+	wpa2, err := WPAConfig().GetWPA2PSK(WPABSS{SSID: "IEEE", PSK: "password"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewProfile("wpa2", wpa2, nil); !errors.Is(err, ErrNoSecretStore) {
+		t.Fatalf("NewProfile without a store = %v, want %v", err, ErrNoSecretStore)
+	}
+}
@@ -0,0 +1,180 @@
+package wpac
+
+import (
+	"fmt"
+)
+
+type codeInfo struct {
+	name        string
+	description string
+}
+
+// reasonCodes IEEE 802.11 reason codes (Table 9-45)
+var reasonCodes = map[int32]codeInfo{
+	1:  {"UNSPECIFIED", "Unspecified reason"},
+	2:  {"PREV_AUTH_NOT_VALID", "Previous authentication no longer valid"},
+	3:  {"DEAUTH_LEAVING", "Deauthenticated because sending STA is leaving or has left"},
+	4:  {"DISASSOC_DUE_TO_INACTIVITY", "Disassociated due to inactivity"},
+	5:  {"DISASSOC_AP_BUSY", "AP is unable to handle all currently associated STAs"},
+	6:  {"CLASS2_FRAME_FROM_NONAUTH_STA", "Class 2 frame received from nonauthenticated STA"},
+	7:  {"CLASS3_FRAME_FROM_NONASSOC_STA", "Class 3 frame received from nonassociated STA"},
+	8:  {"DISASSOC_STA_HAS_LEFT", "Disassociated because sending STA is leaving or has left BSS"},
+	9:  {"STA_REQ_ASSOC_WITHOUT_AUTH", "STA requesting association is not authenticated"},
+	10: {"PWR_CAPABILITY_NOT_VALID", "Power Capability element is unacceptable"},
+	11: {"SUPPORTED_CHANNEL_NOT_VALID", "Supported Channels element is unacceptable"},
+	12: {"BSS_TRANSITION_DISASSOC", "Disassociated due to BSS transition management"},
+	13: {"INVALID_IE", "Invalid element"},
+	14: {"MICHAEL_MIC_FAILURE", "Message integrity code (MIC) failure"},
+	15: {"4WAY_HANDSHAKE_TIMEOUT", "4-Way Handshake timeout — likely wrong PSK"},
+	16: {"GROUP_KEY_UPDATE_TIMEOUT", "Group Key Handshake timeout"},
+	17: {"IE_IN_4WAY_DIFFERS", "Element in 4-Way Handshake different from (Re)Association Request"},
+	18: {"GROUP_CIPHER_NOT_VALID", "Invalid group cipher"},
+	19: {"PAIRWISE_CIPHER_NOT_VALID", "Invalid pairwise cipher"},
+	20: {"AKMP_NOT_VALID", "Invalid AKMP"},
+	21: {"UNSUPPORTED_RSN_IE_VERSION", "Unsupported RSNE version"},
+	22: {"INVALID_RSN_IE_CAPAB", "Invalid RSNE capabilities"},
+	23: {"IEEE_802_1X_AUTH_FAILED", "IEEE 802.1X authentication failed — check EAP credentials"},
+	24: {"CIPHER_SUITE_REJECTED", "Cipher suite rejected because of the security policy"},
+	25: {"TDLS_TEARDOWN_UNREACHABLE", "TDLS direct-link teardown due to TDLS peer STA unreachable"},
+	26: {"TDLS_TEARDOWN_UNSPECIFIED", "TDLS direct-link teardown for unspecified reason"},
+	27: {"SSP_REQUESTED_DISASSOC", "Disassociated because session terminated by SSP request"},
+	28: {"NO_SSP_ROAMING_AGREEMENT", "Disassociated because of lack of SSP roaming agreement"},
+	29: {"BAD_CIPHER_OR_AKM", "Requested service rejected because of SSP cipher suite or AKM requirement"},
+	30: {"NOT_AUTHORIZED_THIS_LOCATION", "Requested service not authorized in this location"},
+	31: {"SERVICE_CHANGE_PRECLUDES_TS", "TS deleted because QoS AP lacks sufficient bandwidth"},
+	32: {"UNSPECIFIED_QOS_REASON", "Disassociated for unspecified, QoS-related reason"},
+	33: {"NOT_ENOUGH_BANDWIDTH", "QoS AP lacks sufficient bandwidth for this QoS STA"},
+	34: {"DISASSOC_LOW_ACK", "Excessive number of frames need to be acknowledged — poor channel conditions"},
+	35: {"EXCEEDED_TXOP", "STA is transmitting outside the limits of its TXOPs"},
+	36: {"STA_LEAVING", "Requesting STA is leaving the BSS or resetting"},
+	37: {"END_TS_BA_DLS", "Requesting STA is no longer using the stream or session"},
+	38: {"UNKNOWN_TS_BA", "Requesting STA received frames using a mechanism for which setup has not been completed"},
+	39: {"TIMEOUT", "Requested from peer STA due to timeout"},
+	45: {"PEERKEY_MISMATCH", "Peer STA does not support the requested cipher suite"},
+	46: {"AUTHORIZED_ACCESS_LIMIT_REACHED", "Disassociated because authorized access limit reached"},
+	47: {"EXTERNAL_SERVICE_REQUIREMENTS", "Disassociated due to external service requirements"},
+	48: {"INVALID_FT_ACTION_FRAME_COUNT", "Invalid FT Action frame count"},
+	49: {"INVALID_PMKID", "Invalid pairwise master key identifier (PMKID)"},
+	50: {"INVALID_MDE", "Invalid MDE"},
+	51: {"INVALID_FTE", "Invalid FTE"},
+}
+
+// statusCodes IEEE 802.11 status codes (Table 9-46)
+var statusCodes = map[int32]codeInfo{
+	0:   {"SUCCESS", "Successful"},
+	1:   {"UNSPECIFIED_FAILURE", "Unspecified failure"},
+	2:   {"TDLS_WAKEUP_ALTERNATE", "TDLS wakeup schedule rejected but alternative schedule provided"},
+	3:   {"TDLS_WAKEUP_REJECT", "TDLS wakeup schedule rejected"},
+	5:   {"SECURITY_DISABLED", "Security disabled"},
+	6:   {"UNACCEPTABLE_LIFETIME", "Unacceptable lifetime"},
+	7:   {"NOT_IN_SAME_BSS", "Not in same BSS"},
+	10:  {"CAPS_UNSUPPORTED", "Cannot support all requested capabilities"},
+	11:  {"REASSOC_NO_ASSOC", "Reassociation denied due to inability to confirm that association exists"},
+	12:  {"ASSOC_DENIED_UNSPEC", "Association denied due to reason outside the scope of this standard"},
+	13:  {"NOT_SUPPORTED_AUTH_ALG", "Authentication algorithm not supported — check auth_alg"},
+	14:  {"UNKNOWN_AUTH_TRANSACTION", "Authentication transaction sequence number out of expected sequence"},
+	15:  {"CHALLENGE_FAIL", "Authentication rejected because of challenge failure — likely wrong WEP key"},
+	16:  {"AUTH_TIMEOUT", "Authentication rejected due to timeout waiting for next frame"},
+	17:  {"AP_UNABLE_TO_HANDLE_NEW_STA", "AP is unable to handle additional associated STAs"},
+	18:  {"ASSOC_DENIED_RATES", "Association denied, STA does not support all basic rates"},
+	19:  {"ASSOC_DENIED_NOSHORT", "Association denied, STA does not support short preamble"},
+	22:  {"SPEC_MGMT_REQUIRED", "Association denied, spectrum management capability required"},
+	23:  {"PWR_CAPABILITY_NOT_VALID", "Association denied, Power Capability element unacceptable"},
+	24:  {"SUPPORTED_CHANNEL_NOT_VALID", "Association denied, Supported Channels element unacceptable"},
+	25:  {"ASSOC_DENIED_NO_SHORT_SLOT_TIME", "Association denied, STA does not support short slot time"},
+	27:  {"ASSOC_DENIED_NO_HT", "Association denied, STA does not support HT features"},
+	28:  {"R0KH_UNREACHABLE", "R0KH unreachable"},
+	29:  {"ASSOC_DENIED_NO_PCO", "Association denied, STA does not support PCO transition time"},
+	30:  {"ASSOC_REJECTED_TEMPORARILY", "Association request rejected temporarily, try again later"},
+	31:  {"ROBUST_MGMT_FRAME_POLICY_VIOLATION", "Robust management frame policy violation — check ieee80211w"},
+	32:  {"UNSPECIFIED_QOS_FAILURE", "Unspecified, QoS-related failure"},
+	33:  {"DENIED_INSUFFICIENT_BANDWIDTH", "QoS AP has insufficient bandwidth to handle another QoS STA"},
+	34:  {"DENIED_POOR_CHANNEL_CONDITIONS", "Association denied due to excessive frame loss rates or poor channel conditions"},
+	35:  {"DENIED_QOS_NOT_SUPPORTED", "Association denied, requesting STA does not support QoS"},
+	37:  {"REQUEST_DECLINED", "The request has been declined"},
+	38:  {"INVALID_PARAMETERS", "The request has not been successful as one or more parameters have invalid values"},
+	40:  {"INVALID_IE", "Invalid element"},
+	41:  {"GROUP_CIPHER_NOT_VALID", "Invalid group cipher"},
+	42:  {"PAIRWISE_CIPHER_NOT_VALID", "Invalid pairwise cipher"},
+	43:  {"AKMP_NOT_VALID", "Invalid AKMP — security type mismatch with the AP"},
+	44:  {"UNSUPPORTED_RSN_IE_VERSION", "Unsupported RSNE version"},
+	45:  {"INVALID_RSN_IE_CAPAB", "Invalid RSNE capabilities"},
+	46:  {"CIPHER_REJECTED_PER_POLICY", "Cipher suite rejected because of security policy"},
+	47:  {"TS_NOT_CREATED", "The TS has not been created"},
+	48:  {"DIRECT_LINK_NOT_ALLOWED", "Direct link is not allowed in the BSS by policy"},
+	49:  {"DEST_STA_NOT_PRESENT", "The Destination STA is not present within this BSS"},
+	50:  {"DEST_STA_NOT_QOS_STA", "The Destination STA is not a QoS STA"},
+	51:  {"ASSOC_DENIED_LISTEN_INT_TOO_LARGE", "Association denied because the listen interval is too large"},
+	52:  {"INVALID_FT_ACTION_FRAME_COUNT", "Invalid FT Action frame count"},
+	53:  {"INVALID_PMKID", "Invalid pairwise master key identifier (PMKID)"},
+	54:  {"INVALID_MDIE", "Invalid MDE"},
+	55:  {"INVALID_FTIE", "Invalid FTE"},
+	72:  {"INVALID_RSNIE", "Invalid RSNE"},
+	76:  {"ANTI_CLOGGING_TOKEN_REQ", "Anti-clogging token required"},
+	77:  {"FINITE_CYCLIC_GROUP_NOT_SUPPORTED", "Finite cyclic group not supported — check sae_groups"},
+	82:  {"REJECTED_WITH_SUGGESTED_BSS_TRANSITION", "Rejected with suggested BSS transition"},
+	93:  {"DENIED_WITH_SUGGESTED_BAND_AND_CHANNEL", "Denied with suggested band and channel"},
+	104: {"ASSOC_DENIED_NO_VHT", "Association denied, STA does not support VHT features"},
+	123: {"UNKNOWN_PASSWORD_IDENTIFIER", "Unknown SAE password identifier — check sae_password_id"},
+	126: {"SAE_HASH_TO_ELEMENT", "SAE hash-to-element in use"},
+	127: {"SAE_PK", "SAE-PK in use"},
+}
+
+// ReasonCode A decoded DisconnectReason. wpa_supplicant reports locally
+// generated disconnections (e.g. Disconnect() or a roam) as negative values.
+type ReasonCode struct {
+	Code        int32  `json:"code"`
+	Locally     bool   `json:"locally_generated"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// StatusCode A decoded authentication or association status code
+type StatusCode struct {
+	Code        int32  `json:"code"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+func DecodeReason(reason int32) ReasonCode {
+	rc := ReasonCode{Code: reason}
+	code := reason
+	if code < 0 {
+		rc.Locally = true
+		code = -code
+	}
+	if info, found := reasonCodes[code]; found {
+		rc.Name = info.name
+		rc.Description = info.description
+	} else if code == 0 {
+		rc.Name = "NONE"
+		rc.Description = "No disconnection"
+	} else {
+		rc.Name = "UNKNOWN"
+		rc.Description = "Unknown reason"
+	}
+	return rc
+}
+
+func (rc ReasonCode) String() string {
+	if rc.Locally {
+		return fmt.Sprintf("%d (%s, locally generated)", rc.Code, rc.Description)
+	}
+	return fmt.Sprintf("%d (%s)", rc.Code, rc.Description)
+}
+
+func DecodeStatus(status int32) StatusCode {
+	sc := StatusCode{Code: status}
+	if info, found := statusCodes[status]; found {
+		sc.Name = info.name
+		sc.Description = info.description
+	} else {
+		sc.Name = "UNKNOWN"
+		sc.Description = "Unknown status"
+	}
+	return sc
+}
+
+func (sc StatusCode) String() string {
+	return fmt.Sprintf("%d (%s)", sc.Code, sc.Description)
+}
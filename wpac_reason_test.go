@@ -0,0 +1,49 @@
+package wpac
+
+import "testing"
+
+func TestDecodeReason(t *testing.T) {
+	tests := []struct {
+		reason  int32
+		name    string
+		locally bool
+	}{
+		{0, "NONE", false},
+		{3, "DEAUTH_LEAVING", false},
+		{-3, "DEAUTH_LEAVING", true},
+		{15, "4WAY_HANDSHAKE_TIMEOUT", false},
+		{23, "IEEE_802_1X_AUTH_FAILED", false},
+		{-23, "IEEE_802_1X_AUTH_FAILED", true},
+		{40, "UNKNOWN", false},
+		{-999, "UNKNOWN", true},
+	}
+	for _, tt := range tests {
+		rc := DecodeReason(tt.reason)
+		if rc.Code != tt.reason || rc.Name != tt.name || rc.Locally != tt.locally {
+			t.Errorf("DecodeReason(%d) = %+v, want name %s locally %v", tt.reason, rc, tt.name, tt.locally)
+		}
+	}
+}
+
+func TestDecodeStatus(t *testing.T) {
+	tests := []struct {
+		status int32
+		name   string
+	}{
+		{0, "SUCCESS"},
+		{15, "CHALLENGE_FAIL"},
+		{77, "FINITE_CYCLIC_GROUP_NOT_SUPPORTED"},
+		{101, "UNKNOWN"},
+		{104, "ASSOC_DENIED_NO_VHT"},
+		{123, "UNKNOWN_PASSWORD_IDENTIFIER"},
+		{126, "SAE_HASH_TO_ELEMENT"},
+		{127, "SAE_PK"},
+		{-1, "UNKNOWN"},
+		{999, "UNKNOWN"},
+	}
+	for _, tt := range tests {
+		if sc := DecodeStatus(tt.status); sc.Name != tt.name {
+			t.Errorf("DecodeStatus(%d) = %s, want %s", tt.status, sc.Name, tt.name)
+		}
+	}
+}
@@ -0,0 +1,325 @@
+package wpac
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/godbus/dbus/v5"
+)
+
+type ReconcileAction string
+
+const (
+	ReconcileAdd    ReconcileAction = "add"
+	ReconcileUpdate ReconcileAction = "update"
+	ReconcileRemove ReconcileAction = "remove"
+	ReconcileKeep   ReconcileAction = "keep"
+)
+
+// secretFields are never reported by wpa_supplicant, they can only be compared
+// with the arguments of networks added through wpac
+var secretFields = map[string]bool{
+	"psk":                true,
+	"password":           true,
+	"private_key_passwd": true,
+	"sae_password":       true,
+	"wep_key0":           true,
+	"wep_key1":           true,
+	"wep_key2":           true,
+	"wep_key3":           true,
+}
+
+// listFields hold space separated values which wpa_supplicant reports in its
+// own order, e.g. "WPA-PSK FT-PSK" for "FT-PSK WPA-PSK"
+var listFields = map[string]bool{
+	WPANetworkKeyMgmt:  true,
+	WPANetworkProto:    true,
+	WPANetworkPairWise: true,
+	WPANetworkGroup:    true,
+	"auth_alg":         true,
+	"eap":              true,
+	"group_mgmt":       true,
+}
+
+// secretTag prefixes the id_str Reconcile sets, it carries a digest of the
+// secrets so changed secrets are found although wpa_supplicant never reports them
+const (
+	secretTag   = "wpac:"
+	secretIDStr = "id_str"
+)
+
+// ReconcileStep One change of a plan, ID is -1 for networks yet to add
+type ReconcileStep struct {
+	Action  ReconcileAction `json:"action"`
+	ID      int             `json:"id"`
+	SSID    string          `json:"ssid"`
+	Profile string          `json:"profile,omitempty"`
+	Fields  []string        `json:"fields,omitempty"`
+	Current bool            `json:"current,omitempty"`
+	profile Profile
+}
+
+// ReconcilePlan What Reconcile does, or did if Applied
+type ReconcilePlan struct {
+	Steps   []ReconcileStep `json:"steps"`
+	Applied bool            `json:"applied"`
+}
+
+// Changed reports whether the plan touches any network
+func (p ReconcilePlan) Changed() bool {
+	for _, step := range p.Steps {
+		if step.Action != ReconcileKeep {
+			return true
+		}
+	}
+	return false
+}
+
+func (p ReconcilePlan) String() string {
+	b := strings.Builder{}
+	for _, step := range p.Steps {
+		b.WriteString(fmt.Sprintf("%-6s %3d %s", step.Action, step.ID, step.SSID))
+		if step.Current {
+			b.WriteString(" (current)")
+		}
+		if len(step.Fields) > 0 {
+			b.WriteString(" [" + strings.Join(step.Fields, ", ") + "]")
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// Plan diffs profiles against the networks of wpa_supplicant without changing
+// anything. Networks are matched by SSID, the one with the lowest ID wins and
+// duplicates are removed.
+func (self *WPAInterface) Plan(ctx context.Context, profiles []Profile) (ReconcilePlan, error) {
+	plan := ReconcilePlan{}
+	networks, err := self.GetNetworks()
+	if err != nil {
+		return plan, err
+	}
+	current := self.currentNetworkPath()
+
+	ids := make([]int, 0, len(networks))
+	for id := range networks {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	bySSID := make(map[string]WPANetwork, len(networks))
+	matched := make(map[int]bool, len(networks))
+	for _, id := range ids {
+		if _, found := bySSID[networks[id].SSID]; !found {
+			bySSID[networks[id].SSID] = networks[id]
+		}
+	}
+
+	for _, p := range profiles {
+		if err := ctx.Err(); err != nil {
+			return plan, err
+		}
+		network, found := bySSID[p.SSID()]
+		if !found || matched[network.ID] {
+			plan.Steps = append(plan.Steps, ReconcileStep{Action: ReconcileAdd, ID: -1, SSID: p.SSID(), Profile: p.Name, profile: p})
+			continue
+		}
+		matched[network.ID] = true
+		step := ReconcileStep{
+			Action:  ReconcileKeep,
+			ID:      network.ID,
+			SSID:    network.SSID,
+			Profile: p.Name,
+			Current: network.Object == current,
+			profile: p,
+		}
+		if step.Fields = self.changedFields(network, p.Args()); len(step.Fields) > 0 {
+			step.Action = ReconcileUpdate
+		}
+		plan.Steps = append(plan.Steps, step)
+	}
+	for _, id := range ids {
+		if !matched[id] {
+			network := networks[id]
+			plan.Steps = append(plan.Steps, ReconcileStep{
+				Action:  ReconcileRemove,
+				ID:      id,
+				SSID:    network.SSID,
+				Current: network.Object == current,
+			})
+		}
+	}
+	return plan, nil
+}
+
+// Reconcile converges the networks of wpa_supplicant to profiles: missing ones
+// are added, changed ones updated through SetNetwork and the others removed.
+// Unchanged networks, the connected one in particular, aren't touched.
+// Networks with secrets get an id_str digest of them, so the next Plan can
+// tell whether a secret changed; networks without it are rewritten once.
+func (self *WPAInterface) Reconcile(ctx context.Context, profiles []Profile) (ReconcilePlan, error) {
+	plan, err := self.Plan(ctx, profiles)
+	if err != nil {
+		return plan, err
+	}
+	// remove first, so the supplicant doesn't pick a stale network meanwhile
+	for _, action := range []ReconcileAction{ReconcileRemove, ReconcileUpdate, ReconcileAdd} {
+		for _, step := range plan.Steps {
+			if step.Action != action {
+				continue
+			}
+			if err := ctx.Err(); err != nil {
+				return plan, err
+			}
+			switch action {
+			case ReconcileRemove:
+				err = self.RemoveNetwork(step.ID)
+			case ReconcileUpdate:
+				err = self.SetNetwork(step.ID, self.withSecretDigest(step.profile.Args()))
+			case ReconcileAdd:
+				_, err = self.AddNetwork(self.withSecretDigest(step.profile.Args()))
+			}
+			if err != nil {
+				return plan, fmt.Errorf("%s network %s failed (%s)", action, step.SSID, err.Error())
+			}
+		}
+	}
+	plan.Applied = true
+	return plan, nil
+}
+
+// changedFields compares args with the arguments the network was added with
+// if wpac added it, with the properties wpa_supplicant reports otherwise.
+// Secrets are compared through the digest in id_str, see secretDigest, and
+// reported as changed when the network has none or it doesn't match.
+func (self *WPAInterface) changedFields(network WPANetwork, args map[string]dbus.Variant) []string {
+	self.mu.Lock()
+	saved, found := self.saved[network.Object]
+	self.mu.Unlock()
+
+	fields := []string{}
+	secrets := []string{}
+	for k, v := range args {
+		if _, ref := v.Value().(SecretRef); ref || secretFields[k] {
+			secrets = append(secrets, k)
+			continue
+		}
+		if found {
+			if old, ok := saved[k]; !ok || !reflect.DeepEqual(old.Value(), v.Value()) {
+				fields = append(fields, k)
+			}
+			continue
+		}
+		if !sameProperty(k, network.props[k], propertyString(v)) {
+			fields = append(fields, k)
+		}
+	}
+	if len(secrets) > 0 {
+		if _, own := args[secretIDStr]; !own {
+			digest, err := secretDigest(self.bus.secretStore(), args)
+			if err != nil || network.props[secretIDStr] != digest {
+				fields = append(fields, secrets...)
+			}
+		} else {
+			// the profile uses id_str itself, secrets can't be compared
+			fields = append(fields, secrets...)
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// sameProperty compares list fields as sets, other fields as is
+func sameProperty(field, a, b string) bool {
+	if !listFields[field] {
+		return a == b
+	}
+	set := func(value string) map[string]bool {
+		items := make(map[string]bool)
+		for _, item := range strings.Fields(strings.ToUpper(value)) {
+			if field == WPANetworkProto && item == "WPA2" {
+				// WPA2 is an alias of RSN
+				item = "RSN"
+			}
+			items[item] = true
+		}
+		return items
+	}
+	return reflect.DeepEqual(set(a), set(b))
+}
+
+// secretDigest the id_str Reconcile sets on a network with secrets: a digest
+// of the resolved secrets and the SSID, so a rotated secret behind an unchanged
+// SecretRef is found as well.
+func secretDigest(store SecretStore, args map[string]dbus.Variant) (string, error) {
+	resolved, err := resolveSecrets(store, args, "")
+	if err != nil {
+		return "", err
+	}
+	keys := []string{}
+	for k, v := range args {
+		if _, ref := v.Value().(SecretRef); ref || secretFields[k] {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	h := sha256.New()
+	if ssid, ok := args[WPANetworkSSID]; ok {
+		fmt.Fprintf(h, "ssid=%s\n", variantSSID(ssid))
+	}
+	for _, k := range keys {
+		value := resolved[k].Value()
+		if b, ok := value.([]byte); ok {
+			value = hex.EncodeToString(b)
+		}
+		fmt.Fprintf(h, "%s=%v\n", k, value)
+	}
+	return secretTag + hex.EncodeToString(h.Sum(nil))[:16], nil
+}
+
+// withSecretDigest adds the id_str Plan compares secrets with, profiles which
+// set id_str themselves are left alone
+func (self *WPAInterface) withSecretDigest(args map[string]dbus.Variant) map[string]dbus.Variant {
+	if _, own := args[secretIDStr]; own {
+		return args
+	}
+	for k, v := range args {
+		if _, ref := v.Value().(SecretRef); ref || secretFields[k] {
+			if digest, err := secretDigest(self.bus.secretStore(), args); err == nil {
+				args[secretIDStr] = dbus.MakeVariant(digest)
+			}
+			break
+		}
+	}
+	return args
+}
+
+// propertyString formats v like wpa_supplicant reports network properties
+func propertyString(v dbus.Variant) string {
+	switch value := v.Value().(type) {
+	case string:
+		return value
+	case []byte:
+		return string(value)
+	case int32:
+		return strconv.Itoa(int(value))
+	case uint32:
+		return strconv.FormatUint(uint64(value), 10)
+	}
+	return fmt.Sprint(v.Value())
+}
+
+func (self *WPAInterface) currentNetworkPath() dbus.ObjectPath {
+	obj := self.bus.object(self.Path())
+	prop, err := obj.GetProperty("fi.w1.wpa_supplicant1.Interface.CurrentNetwork")
+	if err != nil {
+		return ""
+	}
+	path, _ := prop.Value().(dbus.ObjectPath)
+	return path
+}
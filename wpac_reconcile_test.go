@@ -0,0 +1,71 @@
+package wpac
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/godbus/dbus/v5"
+)
+
+func TestSameProperty(t *testing.T) {
+	tests := []struct {
+		field string
+		a, b  string
+		same  bool
+	}{
+		{WPANetworkKeyMgmt, "WPA-PSK FT-PSK", "FT-PSK WPA-PSK", true},
+		{WPANetworkKeyMgmt, "WPA-PSK", "FT-PSK WPA-PSK", false},
+		{WPANetworkProto, "WPA RSN", "RSN WPA", true},
+		{WPANetworkProto, "RSN", "WPA2", true},
+		{WPANetworkPairWise, "CCMP TKIP", "tkip ccmp", true},
+		{"auth_alg", "OPEN SHARED", "SHARED OPEN", true},
+		{WPANetworkSSID, "a b", "b a", false},
+		{WPANetworkPriority, "5", "5", true},
+	}
+	for _, tt := range tests {
+		if same := sameProperty(tt.field, tt.a, tt.b); same != tt.same {
+			t.Errorf("sameProperty(%s, %q, %q) = %v, want %v", tt.field, tt.a, tt.b, same, tt.same)
+		}
+	}
+}
+
+func TestChangedFieldsSecrets(t *testing.T) {
+	store := NewMemorySecretStore()
+	store.Put("office/psk", "passphrase")
+	iface := &WPAInterface{bus: &WPADBus{secrets: store}, saved: make(map[dbus.ObjectPath]map[string]dbus.Variant)}
+
+	args := func() map[string]dbus.Variant {
+		return map[string]dbus.Variant{
+			WPANetworkSSID:    dbus.MakeVariant("office"),
+			WPANetworkKeyMgmt: dbus.MakeVariant("FT-PSK WPA-PSK"),
+			WPANetworkPSK:     dbus.MakeVariant(SecretRef("office/psk")),
+		}
+	}
+	digest, err := secretDigest(store, args())
+	if err != nil {
+		t.Fatal(err)
+	}
+	network := WPANetwork{Object: "/n/0", props: map[string]string{
+		WPANetworkSSID:    "office",
+		WPANetworkKeyMgmt: "WPA-PSK FT-PSK",
+	}}
+
+	// no digest yet, e.g. a network from wpa_supplicant.conf
+	if fields := iface.changedFields(network, args()); !reflect.DeepEqual(fields, []string{WPANetworkPSK}) {
+		t.Errorf("without digest: changed %v, want [psk]", fields)
+	}
+	network.props[secretIDStr] = digest
+	if fields := iface.changedFields(network, args()); len(fields) != 0 {
+		t.Errorf("matching digest: changed %v, want none", fields)
+	}
+	// the secret behind the unchanged reference was rotated
+	store.Put("office/psk", "new passphrase")
+	if fields := iface.changedFields(network, args()); !reflect.DeepEqual(fields, []string{WPANetworkPSK}) {
+		t.Errorf("rotated secret: changed %v, want [psk]", fields)
+	}
+	// unresolvable secrets are rewritten
+	store.Delete("office/psk")
+	if fields := iface.changedFields(network, args()); !reflect.DeepEqual(fields, []string{WPANetworkPSK}) {
+		t.Errorf("missing secret: changed %v, want [psk]", fields)
+	}
+}
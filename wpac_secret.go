@@ -0,0 +1,111 @@
+package wpac
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"sync/atomic"
+)
+
+const (
+	redacted = "******"
+
+	pskIterations = 4096
+	pskLength     = 32
+)
+
+var revealSecrets int32
+
+var ErrInvalidPassphrase = errors.New("passphrase must be 8..63 characters or 64 hex digits")
+
+// RevealSecrets opts in to print secrets in clear text through String and
+// JSON, e.g. for a debugging session. Secrets are redacted by default.
+func RevealSecrets(reveal bool) {
+	if reveal {
+		atomic.StoreInt32(&revealSecrets, 1)
+	} else {
+		atomic.StoreInt32(&revealSecrets, 0)
+	}
+}
+
+// Secret A passphrase, EAP password or private key password which is
+// redacted in String, GoString and JSON output unless RevealSecrets is on.
+type Secret string
+
+// Reveal returns the clear text, use it only to hand the secret over
+func (s Secret) Reveal() string {
+	return string(s)
+}
+
+func (s Secret) String() string {
+	if s == "" || atomic.LoadInt32(&revealSecrets) == 1 {
+		return string(s)
+	}
+	return redacted
+}
+
+func (s Secret) GoString() string {
+	return `"` + s.String() + `"`
+}
+
+func (s Secret) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+// pbkdf2SHA1 PBKDF2 (RFC 2898) with HMAC-SHA1 as used by IEEE 802.11i
+func pbkdf2SHA1(password, salt []byte, iterations, length int) []byte {
+	prf := hmac.New(sha1.New, password)
+	blocks := (length + prf.Size() - 1) / prf.Size()
+	key := make([]byte, 0, blocks*prf.Size())
+	buf := make([]byte, 4)
+	for block := 1; block <= blocks; block++ {
+		prf.Reset()
+		prf.Write(salt)
+		binary.BigEndian.PutUint32(buf, uint32(block))
+		prf.Write(buf)
+		u := prf.Sum(nil)
+		t := make([]byte, len(u))
+		copy(t, u)
+		for i := 1; i < iterations; i++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for j := range t {
+				t[j] ^= u[j]
+			}
+		}
+		key = append(key, t...)
+	}
+	return key[:length]
+}
+
+// DerivePSK computes the 256-bit WPA PSK from a passphrase and an SSID, the
+// same as wpa_passphrase does. A passphrase which is already 64 hex digits is
+// decoded as is.
+func DerivePSK(passphrase Secret, ssid string) ([]byte, error) {
+	p := passphrase.Reveal()
+	if len(p) == 2*pskLength {
+		if psk, err := hex.DecodeString(p); err == nil {
+			return psk, nil
+		}
+	}
+	if len(p) < 8 || len(p) > 63 {
+		return nil, ErrInvalidPassphrase
+	}
+	if len(ssid) == 0 || len(ssid) > 32 {
+		return nil, errors.New("ssid must be 1..32 bytes")
+	}
+	return pbkdf2SHA1([]byte(p), []byte(ssid), pskIterations, pskLength), nil
+}
+
+// DerivePSKHex is DerivePSK in the 64 hex digits format of wpa_supplicant.conf
+func DerivePSKHex(passphrase Secret, ssid string) (string, error) {
+	psk, err := DerivePSK(passphrase, ssid)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(psk), nil
+}
@@ -0,0 +1,72 @@
+package wpac
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDerivePSK(t *testing.T) {
+	tests := []struct {
+		passphrase Secret
+		ssid       string
+		psk        string
+		err        error
+	}{
+		// IEEE 802.11i-2004 Annex H.4 test vectors
+		{"password", "IEEE", "f42c6fc52df0ebef9ebb4b90b38a5f902e83fe1b135a70e23aed762e9710a12e", nil},
+		{"ThisIsAPassword", "ThisIsASSID", "0dc0d6eb90555ed6419756b9a15ec3e3209b63df707dd508d14581f8982721af", nil},
+		// a raw PSK is taken as is
+		{"f42c6fc52df0ebef9ebb4b90b38a5f902e83fe1b135a70e23aed762e9710a12e", "other", "f42c6fc52df0ebef9ebb4b90b38a5f902e83fe1b135a70e23aed762e9710a12e", nil},
+		{"short", "IEEE", "", ErrInvalidPassphrase},
+		{"", "IEEE", "", ErrInvalidPassphrase},
+		{"this passphrase is sixty four characters long, which is too long", "IEEE", "", ErrInvalidPassphrase},
+	}
+	for _, tt := range tests {
+		psk, err := DerivePSKHex(tt.passphrase, tt.ssid)
+		if tt.err != nil {
+			if !errors.Is(err, tt.err) {
+				t.Errorf("DerivePSKHex(%q, %q) error %v, want %v", tt.passphrase.Reveal(), tt.ssid, err, tt.err)
+			}
+			continue
+		}
+		if err != nil || psk != tt.psk {
+			t.Errorf("DerivePSKHex(%q, %q) = %s, %v, want %s", tt.passphrase.Reveal(), tt.ssid, psk, err, tt.psk)
+		}
+	}
+}
+
+func TestPSKTemplatesNeedAKey(t *testing.T) {
+	bss := WPABSS{SSID: "IEEE", PSK: "short"}
+	if _, err := WPAConfig().GetWPAPSK(bss); !errors.Is(err, ErrInvalidPassphrase) {
+		t.Errorf("GetWPAPSK error %v, want ErrInvalidPassphrase", err)
+	}
+	if _, err := WPAConfig().GetWPA2PSK(bss); !errors.Is(err, ErrInvalidPassphrase) {
+		t.Errorf("GetWPA2PSK error %v, want ErrInvalidPassphrase", err)
+	}
+	if _, err := WPAConfig().GetWPAWPA2PSK(bss); !errors.Is(err, ErrInvalidPassphrase) {
+		t.Errorf("GetWPAWPA2PSK error %v, want ErrInvalidPassphrase", err)
+	}
+	if _, err := WPAConfig().GetFTPSK(bss); !errors.Is(err, ErrInvalidPassphrase) {
+		t.Errorf("GetFTPSK error %v, want ErrInvalidPassphrase", err)
+	}
+	// the deprecated templates keep their signature and leave psk unset
+	if template := WPAConfig().GetWPA2(bss); template == nil || template[WPANetworkPSK].Value() != nil {
+		t.Errorf("GetWPA2 template %v, want one without psk", template)
+	}
+
+	bss.PSK = "password"
+	template, err := WPAConfig().GetWPA2PSK(bss)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if psk, ok := template[WPANetworkPSK].Value().([]byte); !ok || len(psk) != 32 {
+		t.Errorf("GetWPA2PSK psk %v, want the 32 byte derived key", template[WPANetworkPSK])
+	}
+	bss.PSK, bss.PSKRef = "", "office/psk"
+	if template, err = WPAConfig().GetWPA2PSK(bss); err != nil {
+		t.Fatal(err)
+	}
+	if ref, ok := template[WPANetworkPSK].Value().(SecretRef); !ok || ref != "office/psk" {
+		t.Errorf("GetWPA2PSK psk %v, want the reference", template[WPANetworkPSK])
+	}
+}
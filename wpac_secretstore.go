@@ -0,0 +1,265 @@
+package wpac
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/godbus/dbus/v5"
+)
+
+var (
+	ErrSecretNotFound = errors.New("secret not found")
+	ErrNoSecretStore  = errors.New("no secret store configured")
+)
+
+const secretKeyLength = 32
+
+// SecretRef A credential reference which is resolved through the SecretStore
+// of the connection right before a profile is written to wpa_supplicant, so
+// saved profiles never hold the clear text.
+type SecretRef string
+
+// SecretStore keeps credentials by reference, e.g. "office/psk"
+type SecretStore interface {
+	Get(ref string) (Secret, error)
+	Put(ref string, secret Secret) error
+	Delete(ref string) error
+}
+
+// MemorySecretStore keeps secrets for the lifetime of the process
+type MemorySecretStore struct {
+	mu      sync.Mutex
+	secrets map[string]Secret
+}
+
+func NewMemorySecretStore() *MemorySecretStore {
+	return &MemorySecretStore{secrets: make(map[string]Secret)}
+}
+
+func (m *MemorySecretStore) Get(ref string) (Secret, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	secret, ok := m.secrets[ref]
+	if !ok {
+		return "", fmt.Errorf("%w (%s)", ErrSecretNotFound, ref)
+	}
+	return secret, nil
+}
+
+func (m *MemorySecretStore) Put(ref string, secret Secret) error {
+	m.mu.Lock()
+	m.secrets[ref] = secret
+	m.mu.Unlock()
+	return nil
+}
+
+func (m *MemorySecretStore) Delete(ref string) error {
+	m.mu.Lock()
+	delete(m.secrets, ref)
+	m.mu.Unlock()
+	return nil
+}
+
+// FileSecretStore keeps secrets AES-256-GCM encrypted in a JSON file. The
+// reference is authenticated along with each secret, so entries can't be
+// swapped inside the file.
+type FileSecretStore struct {
+	mu      sync.Mutex
+	path    string
+	aead    cipher.AEAD
+	secrets map[string]string
+}
+
+// NewFileSecretStore opens the store at path, which is created on the first Put.
+// key must be 32 bytes, see LoadSecretKey and SecretKeyFromEnv.
+func NewFileSecretStore(path string, key []byte) (*FileSecretStore, error) {
+	if len(key) != secretKeyLength {
+		return nil, fmt.Errorf("secret key must be %d bytes", secretKeyLength)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	fs := &FileSecretStore{path: path, aead: aead, secrets: make(map[string]string)}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return fs, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &fs.secrets); err != nil {
+		return nil, fmt.Errorf("invalid secret store %s (%s)", path, err.Error())
+	}
+	return fs, nil
+}
+
+func (fs *FileSecretStore) Get(ref string) (Secret, error) {
+	fs.mu.Lock()
+	sealed, ok := fs.secrets[ref]
+	fs.mu.Unlock()
+	if !ok {
+		return "", fmt.Errorf("%w (%s)", ErrSecretNotFound, ref)
+	}
+	data, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil || len(data) < fs.aead.NonceSize() {
+		return "", fmt.Errorf("secret %s is corrupted", ref)
+	}
+	nonce, ciphertext := data[:fs.aead.NonceSize()], data[fs.aead.NonceSize():]
+	plain, err := fs.aead.Open(nil, nonce, ciphertext, []byte(ref))
+	if err != nil {
+		return "", fmt.Errorf("decrypt secret %s failed (%s)", ref, err.Error())
+	}
+	return Secret(plain), nil
+}
+
+func (fs *FileSecretStore) Put(ref string, secret Secret) error {
+	nonce := make([]byte, fs.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	sealed := fs.aead.Seal(nonce, nonce, []byte(secret.Reveal()), []byte(ref))
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	fs.secrets[ref] = base64.StdEncoding.EncodeToString(sealed)
+	return fs.save()
+}
+
+func (fs *FileSecretStore) Delete(ref string) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if _, ok := fs.secrets[ref]; !ok {
+		return nil
+	}
+	delete(fs.secrets, ref)
+	return fs.save()
+}
+
+// save replaces the file atomically, the caller holds fs.mu
+func (fs *FileSecretStore) save() error {
+	data, err := json.MarshalIndent(fs.secrets, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(fs.path, data, 0600)
+}
+
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// LoadSecretKey reads a key file holding 32 raw bytes, 64 hex digits or base64
+func LoadSecretKey(path string) ([]byte, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == secretKeyLength {
+		return data, nil
+	}
+	return parseSecretKey(strings.TrimSpace(string(data)))
+}
+
+// SecretKeyFromEnv reads a hex or base64 key from the environment variable name
+func SecretKeyFromEnv(name string) ([]byte, error) {
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return nil, fmt.Errorf("%s is not set", name)
+	}
+	return parseSecretKey(strings.TrimSpace(value))
+}
+
+func parseSecretKey(s string) ([]byte, error) {
+	if key, err := hex.DecodeString(s); err == nil && len(key) == secretKeyLength {
+		return key, nil
+	}
+	if key, err := base64.StdEncoding.DecodeString(s); err == nil && len(key) == secretKeyLength {
+		return key, nil
+	}
+	return nil, fmt.Errorf("secret key must be %d bytes in hex or base64", secretKeyLength)
+}
+
+// resolveSecrets returns a copy of args with every SecretRef replaced by the
+// stored secret, hex WEP keys are sent as bytes and a psk reference is turned into the PSK derived with the ssid
+// of args, or ssid if args doesn't change it.
+func resolveSecrets(store SecretStore, args map[string]dbus.Variant, ssid string) (map[string]dbus.Variant, error) {
+	resolved := make(map[string]dbus.Variant, len(args))
+	for k, v := range args {
+		ref, ok := v.Value().(SecretRef)
+		if !ok {
+			resolved[k] = v
+			continue
+		}
+		if store == nil {
+			return nil, fmt.Errorf("%w (%s)", ErrNoSecretStore, ref)
+		}
+		secret, err := store.Get(string(ref))
+		if err != nil {
+			return nil, err
+		}
+		if isWEPKey(k) && (len(secret) == 10 || len(secret) == 26) {
+			// hex WEP keys, ASCII ones have 5 or 13 characters
+			if key, err := hex.DecodeString(secret.Reveal()); err == nil {
+				resolved[k] = dbus.MakeVariant(key)
+				continue
+			}
+		}
+		if k != WPANetworkPSK {
+			resolved[k] = dbus.MakeVariant(secret.Reveal())
+			continue
+		}
+		if v, ok := args[WPANetworkSSID]; ok {
+			ssid = variantSSID(v)
+		}
+		psk, err := DerivePSK(secret, ssid)
+		if err != nil {
+			return nil, fmt.Errorf("secret %s: %s", ref, err.Error())
+		}
+		resolved[k] = dbus.MakeVariant(psk)
+	}
+	return resolved, nil
+}
+
+func variantSSID(v dbus.Variant) string {
+	switch ssid := v.Value().(type) {
+	case string:
+		return ssid
+	case []byte:
+		return string(ssid)
+	}
+	return ""
+}
@@ -0,0 +1,130 @@
+package wpac
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/godbus/dbus/v5"
+)
+
+func testSecretKey(b byte) []byte {
+	return bytes.Repeat([]byte{b}, secretKeyLength)
+}
+
+func TestFileSecretStoreRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secrets.json")
+	fs, err := NewFileSecretStore(path, testSecretKey(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Put("office/psk", "passphrase"); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err := NewFileSecretStore(path, testSecretKey(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secret, err := reopened.Get("office/psk"); err != nil || secret != "passphrase" {
+		t.Fatalf("Get after reopening = %q, %v, want the stored secret", secret.Reveal(), err)
+	}
+	if err := reopened.Delete("office/psk"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := reopened.Get("office/psk"); err == nil {
+		t.Fatal("Get succeeded after Delete")
+	}
+}
+
+func TestFileSecretStoreRejectsMovedEntry(t *testing.T) {
+	fs, err := NewFileSecretStore(filepath.Join(t.TempDir(), "secrets.json"), testSecretKey(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Put("office/psk", "passphrase"); err != nil {
+		t.Fatal(err)
+	}
+	// the reference is authenticated, a copied entry doesn't decrypt
+	fs.secrets["guest/psk"] = fs.secrets["office/psk"]
+	if secret, err := fs.Get("guest/psk"); err == nil {
+		t.Fatalf("Get of a copied entry = %q, want an error", secret.Reveal())
+	}
+}
+
+func TestFileSecretStoreWrongKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secrets.json")
+	fs, err := NewFileSecretStore(path, testSecretKey(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Put("office/psk", "passphrase"); err != nil {
+		t.Fatal(err)
+	}
+	other, err := NewFileSecretStore(path, testSecretKey(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secret, err := other.Get("office/psk"); err == nil {
+		t.Fatalf("Get with the wrong key = %q, want an error", secret.Reveal())
+	}
+}
+
+func TestParseSecretKey(t *testing.T) {
+	key := testSecretKey(7)
+	tests := []struct {
+		name  string
+		input string
+		ok    bool
+	}{
+		{"hex", hex.EncodeToString(key), true},
+		{"base64", base64.StdEncoding.EncodeToString(key), true},
+		{"short hex", hex.EncodeToString(key[:16]), false},
+		{"long base64", base64.StdEncoding.EncodeToString(append(key, 0)), false},
+		{"garbage", "not a key", false},
+	}
+	for _, tt := range tests {
+		got, err := parseSecretKey(tt.input)
+		if tt.ok && (err != nil || !bytes.Equal(got, key)) {
+			t.Errorf("%s: parseSecretKey = %x, %v, want %x", tt.name, got, err, key)
+		}
+		if !tt.ok && err == nil {
+			t.Errorf("%s: parseSecretKey accepted %q", tt.name, tt.input)
+		}
+	}
+}
+
+func TestResolveSecretsDerivesPSK(t *testing.T) {
+	store := NewMemorySecretStore()
+	store.Put("office/psk", "password")
+	store.Put("office/password", "eap secret")
+	args := map[string]dbus.Variant{
+		WPANetworkSSID: dbus.MakeVariant("IEEE"),
+		WPANetworkPSK:  dbus.MakeVariant(SecretRef("office/psk")),
+		"password":     dbus.MakeVariant(SecretRef("office/password")),
+	}
+	// the ssid of args wins over the one of the existing network
+	resolved, err := resolveSecrets(store, args, "other")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := DerivePSK("password", "IEEE")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if psk := resolved[WPANetworkPSK].Value(); !reflect.DeepEqual(psk, want) {
+		t.Errorf("resolved psk %x, want %x", psk, want)
+	}
+	if password := resolved["password"].Value(); password != "eap secret" {
+		t.Errorf("resolved password %v, want the stored one", password)
+	}
+	if _, ok := args[WPANetworkPSK].Value().(SecretRef); !ok {
+		t.Error("resolveSecrets changed the arguments")
+	}
+	if _, err := resolveSecrets(nil, args, ""); err == nil {
+		t.Error("resolveSecrets without a store succeeded")
+	}
+}
@@ -0,0 +1,70 @@
+package wpac
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/godbus/dbus/v5"
+)
+
+var (
+	ErrBusUnavailable    = errors.New("dbus system bus unavailable")
+	ErrServiceNotRunning = errors.New("wpa_supplicant is not running on the bus")
+	ErrActivationFailed  = errors.New("wpa_supplicant activation failed")
+	ErrServiceTimeout    = errors.New("timed out waiting for wpa_supplicant")
+)
+
+// waitForService makes sure wpa_supplicant owns its bus name before NewWPA returns
+func waitForService(ctx context.Context, bus *WPADBus, opts options) error {
+	// subscribe before checking the owner so NameOwnerChanged can't slip through
+	signal := bus.Subscribe()
+	defer bus.Unsubscribe(signal)
+
+	up, err := bus.HasOwner()
+	if err != nil {
+		return fmt.Errorf("%w (%s)", ErrBusUnavailable, err.Error())
+	}
+	if up {
+		return nil
+	}
+	if opts.activate {
+		if err := bus.StartService(); err != nil {
+			return fmt.Errorf("%w (%s)", ErrActivationFailed, err.Error())
+		}
+		if up, err := bus.HasOwner(); err == nil && up {
+			return nil
+		}
+		if !opts.wait {
+			return ErrActivationFailed
+		}
+	} else if !opts.wait {
+		return ErrServiceNotRunning
+	}
+
+	if opts.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.timeout)
+		defer cancel()
+	}
+	for {
+		select {
+		case event := <-signal:
+			if isServiceUp(event) {
+				return nil
+			}
+		case <-bus.Done():
+			return ErrBusUnavailable
+		case <-ctx.Done():
+			return fmt.Errorf("%w (%s)", ErrServiceTimeout, ctx.Err().Error())
+		}
+	}
+}
+
+func isServiceUp(event *dbus.Signal) bool {
+	if event.Name != SignalNameOwnerChanged || len(event.Body) < 3 || event.Body[0] != WPAServiceName {
+		return false
+	}
+	owner, _ := event.Body[2].(string)
+	return owner != ""
+}
@@ -2,6 +2,9 @@ package wpac
 
 import (
 	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/godbus/dbus/v5"
 )
@@ -12,48 +15,179 @@ const (
 	SignalPropertiesChanged = "fi.w1.wpa_supplicant1.Interface.PropertiesChanged"
 	SignalInterfaceAdded    = "fi.w1.wpa_supplicant1.InterfaceAdded"
 	SignalInterfaceRemoved  = "fi.w1.wpa_supplicant1.InterfaceRemoved"
+	SignalNameOwnerChanged  = "org.freedesktop.DBus.NameOwnerChanged"
 )
 
+const (
+	signalBufferSize = 10
+	// signalSendTimeout how long dispatch waits for a full subscriber
+	signalSendTimeout = time.Second
+)
+
+// WPASignal fans out the signals of a connection to every subscriber. The
+// subscriber channels survive Rebind, so listeners keep working after the
+// connection to the bus is re-established.
+//
+// A full subscriber holds up dispatch for up to a second before the signal is
+// dropped for it, see Dropped. The channel of Get is shared and nobody has to
+// drain it, signals are dropped for it right away.
 type WPASignal struct {
-	conn   *dbus.Conn
-	signal chan *dbus.Signal
-	paths  map[dbus.ObjectPath]string
+	dropped uint64
+	mu      sync.Mutex
+	conn    *dbus.Conn
+	input   chan *dbus.Signal
+	signal  chan *dbus.Signal
+	subs    map[chan *dbus.Signal]struct{}
+	// matchMu serializes match rule changes, matches counts the users of a rule
+	matchMu sync.Mutex
+	matches map[string]int
 }
 
 func NewWPASignal(conn *dbus.Conn) *WPASignal {
 	ws := WPASignal{
-		conn:   conn,
-		signal: make(chan *dbus.Signal, 10),
-		paths:  make(map[dbus.ObjectPath]string),
+		signal:  make(chan *dbus.Signal, signalBufferSize),
+		subs:    make(map[chan *dbus.Signal]struct{}),
+		matches: make(map[string]int),
 	}
-	ws.conn.Signal(ws.signal)
+	ws.subs[ws.signal] = struct{}{}
+	ws.bind(conn)
 	return &ws
 }
 
+func (ws *WPASignal) bind(conn *dbus.Conn) {
+	ws.conn = conn
+	ws.input = make(chan *dbus.Signal, signalBufferSize)
+	ws.conn.Signal(ws.input)
+	go ws.dispatch(ws.input)
+}
+
+func (ws *WPASignal) dispatch(input chan *dbus.Signal) {
+	for sig := range input {
+		ws.mu.Lock()
+		subs := make([]chan *dbus.Signal, 0, len(ws.subs))
+		for ch := range ws.subs {
+			subs = append(subs, ch)
+		}
+		ws.mu.Unlock()
+		for _, ch := range subs {
+			select {
+			case ch <- sig:
+				continue
+			default:
+			}
+			if ch == ws.signal {
+				continue
+			}
+			timer := time.NewTimer(signalSendTimeout)
+			select {
+			case ch <- sig:
+			case <-timer.C:
+				atomic.AddUint64(&ws.dropped, 1)
+			}
+			timer.Stop()
+		}
+	}
+}
+
+// Dropped counts the signals subscribers missed because they didn't drain their channel
+func (ws *WPASignal) Dropped() uint64 {
+	return atomic.LoadUint64(&ws.dropped)
+}
+
+func (ws *WPASignal) connection() *dbus.Conn {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	return ws.conn
+}
+
+// Rebind moves the signal hooks to a new connection, e.g. after dbus-daemon restarted
+func (ws *WPASignal) Rebind(conn *dbus.Conn) error {
+	ws.matchMu.Lock()
+	defer ws.matchMu.Unlock()
+	ws.mu.Lock()
+	ws.conn.RemoveSignal(ws.input)
+	ws.bind(conn)
+	ws.mu.Unlock()
+
+	for match := range ws.matches {
+		if call := conn.BusObject().Call("org.freedesktop.DBus.AddMatch", 0, match); call.Err != nil {
+			return call.Err
+		}
+	}
+	return nil
+}
+
 func (ws *WPASignal) Get() chan *dbus.Signal {
 	return ws.signal
 }
 
+// Subscribe returns an extra signal channel so internal listeners don't steal
+// signals from the channel returned by Get.
+func (ws *WPASignal) Subscribe() chan *dbus.Signal {
+	ch := make(chan *dbus.Signal, signalBufferSize)
+	ws.mu.Lock()
+	ws.subs[ch] = struct{}{}
+	ws.mu.Unlock()
+	return ch
+}
+
+func (ws *WPASignal) Unsubscribe(ch chan *dbus.Signal) {
+	ws.mu.Lock()
+	delete(ws.subs, ch)
+	ws.mu.Unlock()
+}
+
 func (ws *WPASignal) Close() {
-	for path, iface := range ws.paths {
-		ws.RemoveObserver(iface, path)
+	ws.matchMu.Lock()
+	for match := range ws.matches {
+		ws.connection().BusObject().Call("org.freedesktop.DBus.RemoveMatch", 0, match)
+		delete(ws.matches, match)
 	}
-	ws.conn.RemoveSignal(ws.signal)
+	ws.matchMu.Unlock()
+	ws.mu.Lock()
+	ws.conn.RemoveSignal(ws.input)
+	ws.mu.Unlock()
 }
 
-func (ws *WPASignal) AddObserver(iface string, path dbus.ObjectPath) error {
-	match := fmt.Sprintf("type='signal',interface='%s',path='%s'", iface, path)
-	if call := ws.conn.BusObject().Call("org.freedesktop.DBus.AddMatch", 0, match); call.Err != nil {
-		return call.Err
+// AddMatch registers a raw match rule which is restored by Rebind. Rules are
+// counted, the bus only sees the first AddMatch and the last RemoveMatch.
+func (ws *WPASignal) AddMatch(match string) error {
+	ws.matchMu.Lock()
+	defer ws.matchMu.Unlock()
+	if ws.matches[match] == 0 {
+		if call := ws.connection().BusObject().Call("org.freedesktop.DBus.AddMatch", 0, match); call.Err != nil {
+			return call.Err
+		}
 	}
-	ws.paths[path] = iface
+	ws.matches[match]++
 	return nil
 }
 
-func (ws *WPASignal) RemoveObserver(iface string, path dbus.ObjectPath) error {
-	match := fmt.Sprintf("type='signal',interface='%s',path='%s'", iface, path)
-	if call := ws.conn.BusObject().Call("org.freedesktop.DBus.RemoveMatch", 0, match); call.Err != nil {
-		return call.Err
+func (ws *WPASignal) RemoveMatch(match string) error {
+	ws.matchMu.Lock()
+	defer ws.matchMu.Unlock()
+	switch ws.matches[match] {
+	case 0:
+		return nil
+	case 1:
+		if call := ws.connection().BusObject().Call("org.freedesktop.DBus.RemoveMatch", 0, match); call.Err != nil {
+			return call.Err
+		}
+		delete(ws.matches, match)
+	default:
+		ws.matches[match]--
 	}
 	return nil
 }
+
+func (ws *WPASignal) AddObserver(iface string, path dbus.ObjectPath) error {
+	return ws.AddMatch(observerMatch(iface, path))
+}
+
+func (ws *WPASignal) RemoveObserver(iface string, path dbus.ObjectPath) error {
+	return ws.RemoveMatch(observerMatch(iface, path))
+}
+
+func observerMatch(iface string, path dbus.ObjectPath) string {
+	return fmt.Sprintf("type='signal',interface='%s',path='%s'", iface, path)
+}
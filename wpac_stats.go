@@ -0,0 +1,356 @@
+package wpac
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+const defaultStatsPollInterval = 30 * time.Second
+
+// NetworkStats The connection history of a network, keyed by SSID so it
+// survives network IDs changing across supplicant restarts
+type NetworkStats struct {
+	SSID             string         `json:"ssid"`
+	Attempts         int            `json:"attempts"`
+	Successes        int            `json:"successes"`
+	Failures         int            `json:"failures"`
+	FailuresByReason map[string]int `json:"failures_by_reason,omitempty"`
+	Disconnections   int            `json:"disconnections"`
+	LastConnected    time.Time      `json:"last_connected"`
+	TimeConnected    time.Duration  `json:"time_connected"`
+	AverageRSSI      float64        `json:"average_rssi"`
+	RSSISamples      int            `json:"rssi_samples"`
+}
+
+// SuccessRate successes per attempt, 0 without attempts
+func (s NetworkStats) SuccessRate() float64 {
+	if s.Attempts == 0 {
+		return 0
+	}
+	return float64(s.Successes) / float64(s.Attempts)
+}
+
+// linkTrack What StatsStore follows of an interface between events
+type linkTrack struct {
+	ssid       string
+	attempting bool
+	// connected until the Disconnected of the session was counted
+	connected bool
+	since     time.Time
+}
+
+// StatsStore maintains NetworkStats from the events of a WPA, see Run. With a
+// path it's persisted as JSON after every connection event.
+type StatsStore struct {
+	mu    sync.Mutex
+	path  string
+	stats map[string]*NetworkStats
+	links map[string]*linkTrack
+	wpa   *WPA
+	dirty bool
+}
+
+// NewStatsStore keeps the statistics in memory only
+func NewStatsStore() *StatsStore {
+	return &StatsStore{stats: make(map[string]*NetworkStats), links: make(map[string]*linkTrack)}
+}
+
+// OpenStatsStore loads the statistics at path, which is created on the first save
+func OpenStatsStore(path string) (*StatsStore, error) {
+	s := NewStatsStore()
+	s.path = path
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	} else if err != nil {
+		return nil, err
+	}
+	list := []*NetworkStats{}
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, err
+	}
+	for _, stats := range list {
+		s.stats[stats.SSID] = stats
+	}
+	return s, nil
+}
+
+// Get returns a copy of the statistics of ssid
+func (s *StatsStore) Get(ssid string) (NetworkStats, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stats, found := s.stats[ssid]
+	if !found {
+		return NetworkStats{}, false
+	}
+	return s.snapshot(stats), true
+}
+
+// List returns the statistics of every network sorted by SSID
+func (s *StatsStore) List() []NetworkStats {
+	s.mu.Lock()
+	list := make([]NetworkStats, 0, len(s.stats))
+	for _, stats := range s.stats {
+		list = append(list, s.snapshot(stats))
+	}
+	s.mu.Unlock()
+	sort.Slice(list, func(i, j int) bool { return list[i].SSID < list[j].SSID })
+	return list
+}
+
+// snapshot copies stats including the time of a running session, the caller holds s.mu
+func (s *StatsStore) snapshot(stats *NetworkStats) NetworkStats {
+	c := *stats
+	c.FailuresByReason = make(map[string]int, len(stats.FailuresByReason))
+	for k, v := range stats.FailuresByReason {
+		c.FailuresByReason[k] = v
+	}
+	for _, link := range s.links {
+		if link.ssid == stats.SSID && !link.since.IsZero() {
+			c.TimeConnected += time.Since(link.since)
+		}
+	}
+	return c
+}
+
+// Save writes the statistics if the store has a path
+func (s *StatsStore) Save() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.save()
+}
+
+// save the caller holds s.mu
+func (s *StatsStore) save() error {
+	if s.path == "" || !s.dirty {
+		return nil
+	}
+	list := make([]*NetworkStats, 0, len(s.stats))
+	for _, stats := range s.stats {
+		list = append(list, stats)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].SSID < list[j].SSID })
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(s.path, data, 0644); err != nil {
+		return err
+	}
+	s.dirty = false
+	return nil
+}
+
+// Run follows the events of wpa until ctx is done and samples the RSSI of
+// connected interfaces every interval, 0 means every 30 seconds.
+func (s *StatsStore) Run(ctx context.Context, wpa *WPA, interval time.Duration) error {
+	if interval <= 0 {
+		interval = defaultStatsPollInterval
+	}
+	s.mu.Lock()
+	s.wpa = wpa
+	s.mu.Unlock()
+	events := wpa.SubscribeEvents()
+	defer wpa.UnsubscribeEvents(events)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case event := <-events:
+			s.update(event, s.eventSSID(event))
+		case <-ticker.C:
+			s.sampleRSSI()
+		case <-ctx.Done():
+			s.mu.Lock()
+			for _, link := range s.links {
+				s.endSession(link, time.Now())
+			}
+			err := s.save()
+			s.mu.Unlock()
+			return err
+		}
+	}
+}
+
+func (s *StatsStore) network(ssid string) *NetworkStats {
+	stats, found := s.stats[ssid]
+	if !found {
+		stats = &NetworkStats{SSID: ssid, FailuresByReason: make(map[string]int)}
+		s.stats[ssid] = stats
+	}
+	if stats.FailuresByReason == nil {
+		stats.FailuresByReason = make(map[string]int)
+	}
+	return stats
+}
+
+func (s *StatsStore) link(ifname string) *linkTrack {
+	link, found := s.links[ifname]
+	if !found {
+		link = &linkTrack{}
+		s.links[ifname] = link
+	}
+	return link
+}
+
+// eventSSID the network an attempt or connection is on. It's resolved
+// through D-Bus before update takes s.mu, as sampleRSSI does.
+func (s *StatsStore) eventSSID(event WPAEvent) string {
+	switch ev := event.(type) {
+	case StateChanged:
+		if ev.State == "authenticating" || ev.State == "associating" {
+			return s.currentSSID(ev.Ifname)
+		}
+	case Connected:
+		return s.currentSSID(ev.Ifname)
+	}
+	return ""
+}
+
+// currentSSID the network wpa_supplicant works on, also while associating
+func (s *StatsStore) currentSSID(ifname string) string {
+	s.mu.Lock()
+	wpa := s.wpa
+	s.mu.Unlock()
+	if wpa == nil {
+		return ""
+	}
+	iface := wpa.GetInterface(ifname)
+	if iface == nil {
+		return ""
+	}
+	path := iface.currentNetworkPath()
+	if path == "" || path == "/" {
+		return ""
+	}
+	return NewWPANetwork(iface.bus, path).SSID
+}
+
+// endSession adds the running session to the connected time, the caller holds s.mu
+func (s *StatsStore) endSession(link *linkTrack, now time.Time) {
+	if link.since.IsZero() || link.ssid == "" {
+		return
+	}
+	s.network(link.ssid).TimeConnected += now.Sub(link.since)
+	link.since = time.Time{}
+	s.dirty = true
+}
+
+// update applies event, ssid is the current network for StateChanged and
+// Connected, see eventSSID
+func (s *StatsStore) update(event WPAEvent, ssid string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	switch ev := event.(type) {
+	case StateChanged:
+		link := s.link(ev.Ifname)
+		if ev.Previous == "completed" {
+			s.endSession(link, now)
+		}
+		switch ev.State {
+		case "authenticating", "associating":
+			// a roam starts from completed, only idle states start an attempt
+			switch ev.Previous {
+			case "", "disconnected", "inactive", "scanning", "interface_disabled":
+				if link.ssid = ssid; link.ssid != "" {
+					link.attempting, link.connected = true, false
+					s.network(link.ssid).Attempts++
+					s.dirty = true
+				}
+			}
+		}
+	case Connected:
+		link := s.link(ev.Ifname)
+		if ssid != "" {
+			link.ssid = ssid
+		}
+		if link.ssid == "" {
+			return
+		}
+		stats := s.network(link.ssid)
+		if link.attempting {
+			stats.Successes++
+			link.attempting = false
+		}
+		stats.LastConnected = now
+		link.since, link.connected = now, true
+		s.dirty = true
+	case AuthFailed:
+		link := s.link(ev.Ifname)
+		if !link.attempting || link.ssid == "" {
+			return
+		}
+		// AuthFailed carries the DisconnectReason of the attempt, if any
+		key := ev.State
+		if ev.Reason != 0 {
+			key = reasonKey(ev.Reason)
+		}
+		stats := s.network(link.ssid)
+		stats.Failures++
+		stats.FailuresByReason[key]++
+		link.attempting = false
+		s.dirty = true
+	case Disconnected:
+		// failed attempts are counted by AuthFailed, only established links disconnect
+		link := s.link(ev.Ifname)
+		if !link.connected || link.ssid == "" {
+			return
+		}
+		s.network(link.ssid).Disconnections++
+		link.connected = false
+		s.dirty = true
+	case ServiceDown:
+		for _, link := range s.links {
+			s.endSession(link, now)
+			link.attempting, link.connected = false, false
+		}
+	default:
+		return
+	}
+	s.save()
+}
+
+// reasonKey names a DisconnectReason, e.g. "4WAY_HANDSHAKE_TIMEOUT"
+func reasonKey(reason int32) string {
+	rc := DecodeReason(reason)
+	if rc.Name == "UNKNOWN" {
+		return fmt.Sprintf("UNKNOWN_%d", reason)
+	}
+	return rc.Name
+}
+
+func (s *StatsStore) sampleRSSI() {
+	s.mu.Lock()
+	connected := map[string]string{}
+	for ifname, link := range s.links {
+		if !link.since.IsZero() && link.ssid != "" {
+			connected[ifname] = link.ssid
+		}
+	}
+	wpa := s.wpa
+	s.mu.Unlock()
+
+	for ifname, ssid := range connected {
+		iface := wpa.GetInterface(ifname)
+		if iface == nil {
+			continue
+		}
+		lq, err := iface.SignalPoll()
+		if err != nil || lq.RSSI == 0 {
+			continue
+		}
+		s.mu.Lock()
+		stats := s.network(ssid)
+		stats.AverageRSSI = (stats.AverageRSSI*float64(stats.RSSISamples) + float64(lq.RSSI)) / float64(stats.RSSISamples+1)
+		stats.RSSISamples++
+		s.dirty = true
+		s.mu.Unlock()
+	}
+}
@@ -0,0 +1,112 @@
+package wpac
+
+import (
+	"reflect"
+	"testing"
+)
+
+// statsEvent an event and the SSID eventSSID would resolve for it
+type statsEvent struct {
+	event WPAEvent
+	ssid  string
+}
+
+func attempt(ssid string) []statsEvent {
+	return []statsEvent{
+		{StateChanged{Ifname: "wlan0", State: "scanning", Previous: "disconnected"}, ""},
+		{StateChanged{Ifname: "wlan0", State: "associating", Previous: "scanning"}, ssid},
+		{StateChanged{Ifname: "wlan0", State: "associated", Previous: "associating"}, ""},
+		{StateChanged{Ifname: "wlan0", State: "4way_handshake", Previous: "associated"}, ""},
+	}
+}
+
+func connect(ssid string) []statsEvent {
+	return append(attempt(ssid),
+		statsEvent{StateChanged{Ifname: "wlan0", State: "completed", Previous: "4way_handshake"}, ""},
+		statsEvent{Connected{Ifname: "wlan0"}, ssid},
+	)
+}
+
+func sequence(parts ...[]statsEvent) []statsEvent {
+	events := []statsEvent{}
+	for _, part := range parts {
+		events = append(events, part...)
+	}
+	return events
+}
+
+func TestStatsUpdate(t *testing.T) {
+	tests := []struct {
+		name   string
+		events []statsEvent
+		want   NetworkStats
+	}{
+		{"connect and disconnect", sequence(connect("office"), []statsEvent{
+			{Disconnected{Ifname: "wlan0", Reason: 3}, ""},
+			{StateChanged{Ifname: "wlan0", State: "disconnected", Previous: "completed"}, ""},
+		}), NetworkStats{Attempts: 1, Successes: 1, Disconnections: 1, FailuresByReason: map[string]int{}}},
+		{"wrong psk", sequence(attempt("office"), []statsEvent{
+			{Disconnected{Ifname: "wlan0", Reason: 15}, ""},
+			{StateChanged{Ifname: "wlan0", State: "disconnected", Previous: "4way_handshake"}, ""},
+			{AuthFailed{Ifname: "wlan0", State: "4way_handshake", Reason: 15}, ""},
+		}), NetworkStats{Attempts: 1, Failures: 1, FailuresByReason: map[string]int{"4WAY_HANDSHAKE_TIMEOUT": 1}}},
+		{"failure without reason", sequence(attempt("office"), []statsEvent{
+			{AuthFailed{Ifname: "wlan0", State: "associating"}, ""},
+		}), NetworkStats{Attempts: 1, Failures: 1, FailuresByReason: map[string]int{"associating": 1}}},
+		{"retry after failure", sequence(attempt("office"), []statsEvent{
+			{AuthFailed{Ifname: "wlan0", State: "4way_handshake", Reason: 15}, ""},
+		}, connect("office")), NetworkStats{Attempts: 2, Successes: 1, Failures: 1, FailuresByReason: map[string]int{"4WAY_HANDSHAKE_TIMEOUT": 1}}},
+		{"roam is no attempt", sequence(connect("office"), []statsEvent{
+			{StateChanged{Ifname: "wlan0", State: "authenticating", Previous: "completed"}, "office"},
+			{StateChanged{Ifname: "wlan0", State: "completed", Previous: "authenticating"}, ""},
+			{Connected{Ifname: "wlan0"}, "office"},
+		}), NetworkStats{Attempts: 1, Successes: 1, FailuresByReason: map[string]int{}}},
+		{"auth failure without attempt", []statsEvent{
+			{AuthFailed{Ifname: "wlan0", State: "associating", Reason: 2}, ""},
+			{Disconnected{Ifname: "wlan0", Reason: 2}, ""},
+		}, NetworkStats{}},
+		{"service down mid attempt", sequence(attempt("office"), []statsEvent{
+			{ServiceDown{}, ""},
+			{AuthFailed{Ifname: "wlan0", State: "4way_handshake"}, ""},
+		}), NetworkStats{Attempts: 1, FailuresByReason: map[string]int{}}},
+	}
+	for _, tt := range tests {
+		s := NewStatsStore()
+		for _, e := range tt.events {
+			s.update(e.event, e.ssid)
+		}
+		got, found := s.Get("office")
+		if !found {
+			if !reflect.DeepEqual(tt.want, NetworkStats{}) {
+				t.Errorf("%s: no statistics, want %+v", tt.name, tt.want)
+			}
+			continue
+		}
+		got.SSID, got.LastConnected, got.TimeConnected = "", tt.want.LastConnected, 0
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: stats %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestAuthFailed(t *testing.T) {
+	tests := []struct {
+		prev, state string
+		reason      int32
+		failed      bool
+	}{
+		{"4way_handshake", "disconnected", 15, true},
+		{"4way_handshake", "disconnected", 0, true},
+		{"associating", "scanning", 0, false},
+		{"associating", "scanning", 2, true},
+		{"authenticating", "inactive", 0, true},
+		{"associating", "disconnected", -3, false},
+		{"completed", "disconnected", 3, false},
+		{"scanning", "disconnected", 0, false},
+	}
+	for _, tt := range tests {
+		if failed := authFailed(tt.prev, tt.state, tt.reason); failed != tt.failed {
+			t.Errorf("authFailed(%s, %s, %d) = %v, want %v", tt.prev, tt.state, tt.reason, failed, tt.failed)
+		}
+	}
+}
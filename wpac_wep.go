@@ -0,0 +1,94 @@
+package wpac
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	AuthAlgOpen   = "OPEN"
+	AuthAlgShared = "SHARED"
+
+	// WEPDeprecation is reported by ProfileWarnings for every WEP profile
+	WEPDeprecation = "WEP is broken and deprecated (removed from IEEE 802.11-2016), use it only for legacy equipment"
+)
+
+// WEPConfig Static WEP keys, each one 5 or 13 ASCII characters or 10 or 26
+// hex digits (40/104-bit). TxKeyIdx selects the key used to transmit.
+type WEPConfig struct {
+	SSID     string
+	Keys     [4]Secret
+	TxKeyIdx int
+	// AuthAlg is AuthAlgOpen, AuthAlgShared or both separated by a space
+	AuthAlg string
+}
+
+// wepKey ASCII keys are quoted by wpa_supplicant, hex keys go as byte arrays
+// which it stores as raw hex.
+func wepKey(key Secret) (interface{}, error) {
+	k := key.Reveal()
+	switch len(k) {
+	case 5, 13:
+		return k, nil
+	case 10, 26:
+		raw, err := hex.DecodeString(k)
+		if err != nil {
+			return nil, fmt.Errorf("wep key of %d characters must be hex", len(k))
+		}
+		return raw, nil
+	}
+	return nil, fmt.Errorf("wep key must be 5/13 ASCII characters or 10/26 hex digits, got %d", len(k))
+}
+
+// GetWEP Network with static WEP keys.
+//
+// Deprecated: WEP offers no security, it's only kept for legacy equipment.
+// ProfileWarnings reports WEPDeprecation for the returned template.
+func (config *WPASupplicantConfig) GetWEP(wep WEPConfig) (map[string]dbus.Variant, error) {
+	if wep.TxKeyIdx < 0 || wep.TxKeyIdx > 3 {
+		return nil, fmt.Errorf("wep_tx_keyidx must be 0..3, got %d", wep.TxKeyIdx)
+	}
+	if wep.Keys[wep.TxKeyIdx] == "" {
+		return nil, fmt.Errorf("wep_key%d is required by wep_tx_keyidx", wep.TxKeyIdx)
+	}
+	authAlg := wep.AuthAlg
+	if authAlg == "" {
+		authAlg = AuthAlgOpen
+	}
+	for _, alg := range strings.Fields(authAlg) {
+		if alg != AuthAlgOpen && alg != AuthAlgShared {
+			return nil, fmt.Errorf("auth_alg must be %s or %s, got %s", AuthAlgOpen, AuthAlgShared, alg)
+		}
+	}
+
+	template := make(map[string]dbus.Variant)
+	template["ssid"] = dbus.MakeVariant(wep.SSID)
+	template["key_mgmt"] = dbus.MakeVariant("NONE")
+	template["auth_alg"] = dbus.MakeVariant(authAlg)
+	template["wep_tx_keyidx"] = dbus.MakeVariant(int32(wep.TxKeyIdx))
+	for i, key := range wep.Keys {
+		if key == "" {
+			continue
+		}
+		value, err := wepKey(key)
+		if err != nil {
+			return nil, fmt.Errorf("wep_key%d: %s", i, err.Error())
+		}
+		template[fmt.Sprintf("wep_key%d", i)] = dbus.MakeVariant(value)
+	}
+	return template, nil
+}
+
+// ProfileWarnings lists the deprecated settings of a network profile, e.g. WEP
+func ProfileWarnings(profile map[string]dbus.Variant) []string {
+	warnings := []string{}
+	for i := 0; i < 4; i++ {
+		if _, found := profile[fmt.Sprintf("wep_key%d", i)]; found {
+			return append(warnings, WEPDeprecation)
+		}
+	}
+	return warnings
+}